@@ -0,0 +1,393 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/audit"
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/validator"
+)
+
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.UserSearch
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.UserSearch.Query = app.readString(qs, "q", "")
+	input.UserSearch.Activated = app.readString(qs, "activated", "all")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", app.config.defaultPageSize, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafeList = data.UserSortSafeList
+
+	data.ValidateUserSearch(v, input.UserSearch)
+	data.ValidateFilters(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	users, metadata, err := app.models.Users.List(r.Context(), input.UserSearch, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"users": users, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readManagedUser fetches the user named in the URL for the admin endpoints.
+// The placeholder author that holds deleted users' puzzles can't be managed.
+func (app *application) readManagedUser(w http.ResponseWriter, r *http.Request) *data.User {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	user, err := app.models.Users.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+	if user.Email == data.DeletedUserEmail {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+	return user
+}
+
+// readModifiableUser is readManagedUser for the endpoints that change the
+// user. Only superusers can change a superuser's account, so that support
+// staff can't take one over.
+func (app *application) readModifiableUser(w http.ResponseWriter, r *http.Request) *data.User {
+	user := app.readManagedUser(w, r)
+	if user == nil {
+		return nil
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil
+	}
+	if permissions.Include(data.Superuser) {
+		actorPermissions, err := app.models.Permissions.GetAllForUser(r.Context(), app.contextGetUser(r).ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return nil
+		}
+		if !actorPermissions.Include(data.Superuser) {
+			app.notPermittedResponse(w, r)
+			return nil
+		}
+	}
+	return user
+}
+
+func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readManagedUser(w, r)
+	if user == nil {
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if permissions == nil {
+		permissions = data.Permissions{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user, "permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateUserByIdHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readModifiableUser(w, r)
+	if user == nil {
+		return
+	}
+
+	var input struct {
+		FullName    *string `json:"full_name"`
+		DisplayName *string `json:"display_name"`
+		Email       *string `json:"email"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+		Activated   *bool   `json:"activated"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	before := *user
+
+	if input.FullName != nil {
+		user.FullName = *input.FullName
+	}
+	if input.DisplayName != nil {
+		user.DisplayName = *input.DisplayName
+	}
+	if input.Email != nil {
+		user.Email = *input.Email
+	}
+	if input.Bio != nil {
+		user.Bio = *input.Bio
+	}
+	if input.AvatarURL != nil {
+		user.AvatarURL = *input.AvatarURL
+	}
+	if input.Activated != nil {
+		user.Activated = *input.Activated
+	}
+
+	v := validator.New()
+	if data.ValidateUser(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateEmail):
+			v.AddError("email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateDisplayName):
+			v.AddError("display_name", "this display name is already in use at your company")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit.Record(r, audit.Event{
+		Action:     audit.UserUpdated,
+		Actor:      app.contextGetUser(r),
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Details:    audit.Diff(&before, user),
+	})
+
+	// a deactivated user shouldn't stay logged in
+	if !user.Activated {
+		err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeAuthentication, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// resetUserPasswordHandler replaces another user's password and logs them out
+// everywhere. Without a password in the request a temporary one is generated
+// and returned for support staff to pass on.
+func (app *application) resetUserPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readModifiableUser(w, r)
+	if user == nil {
+		return
+	}
+
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	// the body is optional
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	generated := input.Password == ""
+	if generated {
+		input.Password = rand.Text()
+	}
+
+	v := validator.New()
+	data.ValidatePasswordPlaintext(v, input.Password)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := user.Password.Set(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit.Record(r, audit.Event{
+		Action:     audit.UserPasswordReset,
+		Actor:      app.contextGetUser(r),
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]any{"generated": generated},
+	})
+
+	response := envelope{"message": "password has been reset and all sessions revoked"}
+	if generated {
+		response["temporary_password"] = input.Password
+	}
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) revokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readModifiableUser(w, r)
+	if user == nil {
+		return
+	}
+
+	err := app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit.Record(r, audit.Event{
+		Action:     audit.UserSessionsRevoked,
+		Actor:      app.contextGetUser(r),
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "all sessions revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteUserByIdHandler deletes another user's account. Their published
+// puzzles are kept under the placeholder author, as when users delete their
+// own accounts.
+func (app *application) deleteUserByIdHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readModifiableUser(w, r)
+	if user == nil {
+		return
+	}
+
+	actor := app.contextGetUser(r)
+	if user.ID == actor.ID {
+		v := validator.New()
+		v.AddError("id", "use DELETE /v1/user to delete your own account")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Users.Delete(r.Context(), user.ID, false)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit.Record(r, audit.Event{
+		Action:     audit.UserDeleted,
+		Actor:      actor,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.AuditFilter
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.AuditFilter.ActorID = app.readInt(qs, "actor_id", 0, v)
+	input.AuditFilter.Action = app.readString(qs, "action", "")
+	input.AuditFilter.TargetType = app.readString(qs, "target_type", "")
+	input.AuditFilter.TargetID = app.readInt(qs, "target_id", 0, v)
+	input.AuditFilter.CreatedAfter = app.readDateTime(qs, "created_after", time.Time{}, v)
+	input.AuditFilter.CreatedBefore = app.readDateTime(qs, "created_before", time.Time{}, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", app.config.defaultPageSize, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafeList = data.AuditSortSafeList
+
+	data.ValidateAuditFilter(v, input.AuditFilter)
+	data.ValidateFilters(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	events, metadata, err := app.models.Audit.List(r.Context(), input.AuditFilter, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"events": events, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
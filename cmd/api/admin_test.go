@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ggetzie/badwords_be/internal/audit"
+	"github.com/ggetzie/badwords_be/internal/data"
+)
+
+func TestAdminListUsers(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	admin := login(t, app, insertUser(t, app, "admin@example.com", data.UsersAdmin))
+	other := login(t, app, insertUser(t, app, "other@example.com", data.StandardPermissions...))
+
+	code, _ := ts.do(t, http.MethodGet, "/v1/admin/users", other, nil)
+	if code != http.StatusForbidden {
+		t.Errorf("without permission got status %d; want %d", code, http.StatusForbidden)
+	}
+
+	code, response := ts.do(t, http.MethodGet, "/v1/admin/users?q=example.com", admin, nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	// the deleted user placeholder isn't listed
+	if got := len(field(t, response, "users").([]any)); got != 2 {
+		t.Errorf("got %d users; want 2", got)
+	}
+}
+
+func TestAdminUpdateUser(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	admin := login(t, app, insertUser(t, app, "admin@example.com", data.UsersAdmin))
+	user := insertUser(t, app, "bob@example.com", data.UsersRead)
+	token := login(t, app, user)
+	path := fmt.Sprintf("/v1/admin/users/%d", user.ID)
+
+	code, _ := ts.do(t, http.MethodPatch, path, admin, map[string]any{"email": "admin@example.com"})
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("duplicate email got status %d; want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	code, response := ts.do(t, http.MethodPatch, path, admin, map[string]any{"activated": false})
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	if got := field(t, response, "user", "activated"); got != false {
+		t.Errorf("got activated %v; want false", got)
+	}
+
+	// deactivating a user logs them out
+	code, _ = ts.do(t, http.MethodGet, "/v1/user", token, nil)
+	if code != http.StatusUnauthorized {
+		t.Errorf("deactivated user's token got status %d; want %d", code, http.StatusUnauthorized)
+	}
+
+	code, _ = ts.do(t, http.MethodPatch, "/v1/admin/users/1", admin, map[string]any{"full_name": "x"})
+	if code != http.StatusNotFound {
+		t.Errorf("updating the placeholder user got status %d; want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestAdminResetPassword(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	admin := login(t, app, insertUser(t, app, "admin@example.com", data.UsersAdmin))
+	user := insertUser(t, app, "bob@example.com", data.UsersRead)
+	token := login(t, app, user)
+
+	code, response := ts.do(t, http.MethodPost, fmt.Sprintf("/v1/admin/users/%d/password", user.ID), admin, nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	password, _ := field(t, response, "temporary_password").(string)
+	if password == "" {
+		t.Fatal("no temporary password in the response")
+	}
+
+	code, _ = ts.do(t, http.MethodGet, "/v1/user", token, nil)
+	if code != http.StatusUnauthorized {
+		t.Errorf("old token got status %d; want %d", code, http.StatusUnauthorized)
+	}
+	code, _ = ts.do(t, http.MethodPost, "/v1/tokens/authentication", "",
+		map[string]string{"email": user.Email, "password": password})
+	if code != http.StatusCreated {
+		t.Errorf("logging in with the temporary password got status %d; want %d", code, http.StatusCreated)
+	}
+}
+
+func TestAdminSuperuserProtected(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	admin := login(t, app, insertUser(t, app, "admin@example.com", data.UsersAdmin))
+	root := login(t, app, insertUser(t, app, "root@example.com", data.Superuser))
+	superuser := insertUser(t, app, "other-root@example.com", data.Superuser)
+
+	tests := []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{http.MethodPatch, "/v1/admin/users/%d", map[string]any{"email": "mine@example.com"}},
+		{http.MethodPost, "/v1/admin/users/%d/password", nil},
+		{http.MethodDelete, "/v1/admin/users/%d/sessions", nil},
+		{http.MethodDelete, "/v1/admin/users/%d", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			path := fmt.Sprintf(tt.path, superuser.ID)
+			code, _ := ts.do(t, tt.method, path, admin, tt.body)
+			if code != http.StatusForbidden {
+				t.Errorf("as an admin got status %d; want %d", code, http.StatusForbidden)
+			}
+			code, _ = ts.do(t, tt.method, path, root, tt.body)
+			if code != http.StatusOK {
+				t.Errorf("as a superuser got status %d; want %d", code, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestAdminDeleteUser(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	adminUser := insertUser(t, app, "admin@example.com", data.UsersAdmin)
+	admin := login(t, app, adminUser)
+	user := insertUser(t, app, "bob@example.com")
+
+	tests := []struct {
+		name     string
+		id       int
+		wantCode int
+	}{
+		{"Self", adminUser.ID, http.StatusUnprocessableEntity},
+		{"Valid", user.ID, http.StatusOK},
+		{"Already deleted", user.ID, http.StatusNotFound},
+		{"Placeholder", 1, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := ts.do(t, http.MethodDelete, fmt.Sprintf("/v1/admin/users/%d", tt.id), admin, nil)
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	// the audit log mustn't keep the personal data the deletion erased
+	events, _, err := app.models.Audit.List(context.Background(),
+		data.AuditFilter{TargetType: audit.TargetUser, TargetID: user.ID},
+		data.Filters{Page: 1, PageSize: 20, Sort: "created_at", SortSafeList: data.AuditSortSafeList})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 {
+		t.Fatal("the deletion wasn't audited")
+	}
+	for _, event := range events {
+		if len(event.Details) != 0 {
+			t.Errorf("%s event records %v", event.Action, event.Details)
+		}
+	}
+}
+
+func TestListAuditEvents(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	superuser := insertUser(t, app, "root@example.com", data.Superuser)
+	token := login(t, app, superuser)
+	other := login(t, app, insertUser(t, app, "other@example.com", data.UsersRead))
+
+	ts.do(t, http.MethodPost, "/v1/tokens/authentication", "",
+		map[string]string{"email": superuser.Email, "password": "wrongpassword"})
+	ts.do(t, http.MethodPost, "/v1/tokens/authentication", "",
+		map[string]string{"email": superuser.Email, "password": testPassword})
+
+	code, _ := ts.do(t, http.MethodGet, "/v1/admin/audit", other, nil)
+	if code != http.StatusForbidden {
+		t.Errorf("without permission got status %d; want %d", code, http.StatusForbidden)
+	}
+
+	code, response := ts.do(t, http.MethodGet, "/v1/admin/audit?action=auth.login_failed", token, nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	events := field(t, response, "events").([]any)
+	if len(events) != 1 {
+		t.Fatalf("got %d failed logins; want 1", len(events))
+	}
+	if got := events[0].(map[string]any)["target_id"]; got != float64(superuser.ID) {
+		t.Errorf("got target %v; want %d", got, superuser.ID)
+	}
+
+	code, response = ts.do(t, http.MethodGet, "/v1/admin/audit", token, nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	if got := len(field(t, response, "events").([]any)); got != 2 {
+		t.Errorf("got %d events; want 2", got)
+	}
+}
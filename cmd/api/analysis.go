@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+func (app *application) getPuzzleAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	puzzle, err := app.models.Puzzles.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	g, err := puzzle.Content.Grid(puzzle.Width, puzzle.Height)
+	if errors.Is(err, grid.ErrSize) {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "the puzzle is too large to analyze")
+		return
+	}
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "the puzzle's answers don't fit together in its grid: "+err.Error())
+		return
+	}
+	analysis := grid.Analyze(g)
+
+	var answers []string
+	for _, entry := range g.Entries() {
+		word := g.Word(entry)
+		if !strings.ContainsRune(word, rune(grid.Blank)) {
+			answers = append(answers, word)
+		}
+	}
+	uses, err := app.models.Puzzles.AnswerUses(r.Context(), answers, puzzle.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"analysis": analysis, "previously_used": uses}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/validator"
+	"github.com/ggetzie/badwords_be/internal/wordlist"
+)
+
+func (app *application) listCluesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Answer string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Answer = wordlist.Normalize(app.readString(qs, "answer", ""))
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", app.config.defaultPageSize, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-published_at")
+	input.Filters.SortSafeList = data.ClueSortSafeList
+
+	v.Check(input.Answer != "", "answer", "must be provided")
+	v.Check(wordlist.ValidWord(input.Answer), "answer", "must contain between 1 and 25 letters")
+	data.ValidateFilters(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	publishedOnly := !permissions.Include(data.Superuser) && !permissions.Include(data.PuzzlesUpdate)
+
+	clues, metadata, err := app.models.Clues.GetByAnswer(r.Context(), input.Answer, publishedOnly, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"clues": clues, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/validator"
+)
+
+func (app *application) listCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Published string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Published = app.readString(qs, "published", "true")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", app.config.defaultPageSize, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-updated_at")
+	input.Filters.SortSafeList = data.CollectionSortSafeList
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	published1, published2 := data.GetPublished(input.Published)
+
+	user := app.contextGetUser(r)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !(published1 && published2) && !permissions.Include(data.Superuser) && !permissions.Include(data.CollectionsUpdate) {
+		published1 = true
+		published2 = true
+	}
+
+	collections, metadata, err := app.models.Collections.List(r.Context(), published1, published2, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collections": collections, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	collection, err := app.models.Collections.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	privileged := permissions.Include(data.Superuser) || permissions.Include(data.CollectionsUpdate)
+	if !collection.Published && !privileged {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// readers only see the puzzles of a collection that are published
+	puzzles, err := app.models.Collections.GetPuzzles(r.Context(), collection.ID, !privileged)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	results := make([]map[string]any, 0, len(puzzles))
+	for _, puzzle := range puzzles {
+		app.hideAuthorEmail(puzzle, permissions)
+		results = append(results, puzzle.Select(data.PuzzleSummaryFields))
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collection": collection, "puzzles": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkCollectionPuzzles adds a validation error if any of the puzzles don't
+// exist, or if requirePublished is set and any of them are unpublished.
+func (app *application) checkCollectionPuzzles(ctx context.Context, v *validator.Validator, puzzleIDs []int, requirePublished bool) error {
+	if len(puzzleIDs) == 0 {
+		return nil
+	}
+	states, err := app.models.Puzzles.PublishedStates(ctx, puzzleIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range puzzleIDs {
+		published, found := states[id]
+		v.Check(found, "puzzle_ids", fmt.Sprintf("puzzle %d does not exist", id))
+		if found && requirePublished {
+			v.Check(published, "puzzle_ids", fmt.Sprintf("puzzle %d must be published before it can be added to a published collection", id))
+		}
+	}
+	return nil
+}
+
+func (app *application) createCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		CoverURL    string `json:"cover_url"`
+		Published   bool   `json:"published"`
+		PuzzleIDs   []int  `json:"puzzle_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	collection := &data.Collection{
+		Title:       input.Title,
+		Description: input.Description,
+		CoverURL:    input.CoverURL,
+		Published:   input.Published,
+		OwnerID:     user.ID,
+	}
+
+	v := validator.New()
+	data.ValidateCollection(v, collection)
+	data.ValidateCollectionPuzzleIDs(v, input.PuzzleIDs)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkCollectionPuzzles(r.Context(), v, input.PuzzleIDs, collection.Published)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Collections.Insert(r.Context(), collection, input.PuzzleIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/collections/%d", collection.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"collection": collection}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	collection, err := app.models.Collections.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		CoverURL    *string `json:"cover_url"`
+		Published   *bool   `json:"published"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title != nil {
+		collection.Title = *input.Title
+	}
+	if input.Description != nil {
+		collection.Description = *input.Description
+	}
+	if input.CoverURL != nil {
+		collection.CoverURL = *input.CoverURL
+	}
+	if input.Published != nil {
+		collection.Published = *input.Published
+	}
+
+	v := validator.New()
+	if data.ValidateCollection(v, collection); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if collection.Published {
+		unpublished, err := app.models.Collections.UnpublishedPuzzleIDs(r.Context(), collection.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if len(unpublished) > 0 {
+			v.AddError("published", fmt.Sprintf("the collection contains unpublished puzzles %v", unpublished))
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	err = app.models.Collections.Update(r.Context(), collection)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"collection": collection}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) setCollectionPuzzlesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	collection, err := app.models.Collections.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		PuzzleIDs []int `json:"puzzle_ids"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateCollectionPuzzleIDs(v, input.PuzzleIDs); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.checkCollectionPuzzles(r.Context(), v, input.PuzzleIDs, collection.Published)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Collections.SetPuzzles(r.Context(), collection.ID, input.PuzzleIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	puzzles, err := app.models.Collections.GetPuzzles(r.Context(), collection.ID, false)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	results := make([]map[string]any, 0, len(puzzles))
+	for _, puzzle := range puzzles {
+		app.hideAuthorEmail(puzzle, permissions)
+		results = append(results, puzzle.Select(data.PuzzleSummaryFields))
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"puzzles": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Collections.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "collection successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/validator"
+	"gopkg.in/yaml.v3"
+)
+
+type config struct {
+	port       int
+	env        string
+	db         data.DBConfig
+	webBaseURL string
+
+	// keep users and puzzles in memory instead of PostgreSQL
+	inMemory bool
+
+	// what to do at startup when the database isn't at the schema version
+	// the code expects (check|auto|ignore)
+	dbMigrations string
+
+	// how long shutdown waits for requests and background tasks to finish
+	// before cancelling them
+	shutdownTimeout time.Duration
+
+	server struct {
+		idleTimeout  time.Duration
+		readTimeout  time.Duration
+		writeTimeout time.Duration
+	}
+
+	// the largest JSON request body accepted, in bytes
+	maxRequestBody int64
+
+	authTokenTTL time.Duration
+
+	limiter struct {
+		rps     float64
+		burst   int
+		enabled bool
+	}
+
+	defaultPageSize int
+
+	cors struct {
+		trustedOrigins []string
+	}
+
+	metrics struct {
+		// with no port the metrics are served at /metrics on the main port
+		port int
+	}
+
+	tracing struct {
+		exporter    string
+		sampleRatio float64
+	}
+
+	log struct {
+		format string
+		level  string
+	}
+
+	aws struct {
+		accessKeyID     string
+		secretAccessKey string
+	}
+}
+
+// configSetting is a setting that isn't at its default, for logging at
+// startup.
+type configSetting struct {
+	name   string
+	value  string
+	source string
+}
+
+const envPrefix = "BADWORDS_"
+
+// secretSettings are redacted when the configuration is logged.
+var secretSettings = []string{"db-dsn", "aws-access-key-id", "aws-secret-access-key"}
+
+// loadConfig builds the configuration from, in increasing order of
+// precedence, the defaults, a YAML file named by -config or BADWORDS_CONFIG,
+// BADWORDS_* environment variables and the command-line flags. Each flag can
+// be set in the file under its own name, or nested (db: {dsn: ...} for
+// db-dsn), and in the environment as BADWORDS_DB_DSN. Secrets are best kept
+// out of the command line, where they show up in ps.
+func loadConfig(args []string, lookupEnv func(string) (string, bool)) (config, []configSetting, error) {
+	var cfg config
+	var configFile string
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of api:\n\nEvery flag can also be set in the -config file or as a %s environment variable,\ne.g. %s for -db-dsn. Flags take precedence over the environment, which takes precedence over the file.\n\n", envPrefix+"NAME", envName("db-dsn"))
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&configFile, "config", "", "YAML configuration file")
+
+	fs.IntVar(&cfg.port, "port", 8000, "Server port to listen on")
+	fs.StringVar(&cfg.env, "env", "development", "Application environment (development|production)")
+	fs.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 30*time.Second, "Time to wait for requests and background tasks to finish when shutting down")
+
+	// server settings
+	fs.DurationVar(&cfg.server.idleTimeout, "idle-timeout", time.Minute, "Time to keep idle client connections open")
+	fs.DurationVar(&cfg.server.readTimeout, "read-timeout", 5*time.Second, "Maximum time to read a request")
+	fs.DurationVar(&cfg.server.writeTimeout, "write-timeout", 10*time.Second, "Maximum time to write a response")
+	fs.Int64Var(&cfg.maxRequestBody, "max-request-body", 1_048_576, "Largest JSON request body accepted, in bytes")
+	fs.IntVar(&cfg.defaultPageSize, "default-page-size", 20, "Number of results in a page of a listing when the request doesn't say")
+	fs.DurationVar(&cfg.authTokenTTL, "auth-token-ttl", 30*24*time.Hour, "How long authentication tokens are valid for")
+
+	// database connection pool settings
+	fs.StringVar(&cfg.db.DSN, "db-dsn", "", "PostgreSQL DSN")
+	fs.IntVar(&cfg.db.MaxOpenConns, "db-max-open-conns", 25, "PostgreSQL database connection pool max open connections")
+	fs.IntVar(&cfg.db.MinConns, "db-min-conns", 4, "PostgreSQL database connection pool minimum connections")
+	fs.DurationVar(&cfg.db.MaxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL database connection pool max connection idle time")
+	fs.BoolVar(&cfg.inMemory, "in-memory", false, "Keep all data in memory instead of PostgreSQL, for demos and tests")
+	fs.DurationVar(&cfg.db.QueryTimeout, "db-query-timeout", data.DefaultQueryTimeout, "Maximum time a single database query may run")
+	fs.DurationVar(&cfg.db.BulkTimeout, "db-bulk-timeout", data.DefaultBulkTimeout, "Maximum time a database operation over many rows, such as a word list upload, may run")
+	fs.StringVar(&cfg.dbMigrations, "db-migrations", "check", "At startup, refuse to start unless the database is migrated (check), apply pending migrations first (auto) or do neither (ignore)")
+
+	// rate limiter settings
+	fs.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter maximum requests per second")
+	fs.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter burst")
+	fs.BoolVar(&cfg.limiter.enabled, "limiter-enabled", false, "Enable rate limiter")
+
+	// AWS Settings
+	fs.StringVar(&cfg.aws.accessKeyID, "aws-access-key-id", "", "AWS Access Key ID")
+	fs.StringVar(&cfg.aws.secretAccessKey, "aws-secret-access-key", "", "AWS Secret Access Key")
+
+	// CORS settings
+	fs.Func("cors-trusted-origins", "List of trusted origins for CORS (comma separated)", func(val string) error {
+		cfg.cors.trustedOrigins = nil
+		if val != "" {
+			cfg.cors.trustedOrigins = strings.Split(val, ",")
+		}
+		return nil
+	})
+
+	// Metrics settings
+	fs.IntVar(&cfg.metrics.port, "metrics-port", 0, "Serve /metrics on a separate port (0 serves it publicly on the main port)")
+
+	// Tracing settings
+	fs.StringVar(&cfg.tracing.exporter, "trace-exporter", "none", "Trace exporter (none|stdout|otlp)")
+	fs.Float64Var(&cfg.tracing.sampleRatio, "trace-sample-ratio", 1, "Fraction of new traces to sample")
+
+	// Logging settings
+	fs.StringVar(&cfg.log.format, "log-format", "text", "Log format (text|json)")
+	fs.StringVar(&cfg.log.level, "log-level", "", "Minimum log level (debug|info|warn|error), debug in development and info otherwise by default")
+
+	// Base URL - the hostname for the web frontend to build links
+	fs.StringVar(&cfg.webBaseURL, "base-url", "http://localhost:3001", "Base URL for the web frontend")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return cfg, nil, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, nil, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	sources := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		sources[f.Name] = "flag"
+	})
+
+	if configFile == "" {
+		configFile, _ = lookupEnv(envName("config"))
+	}
+	if configFile != "" {
+		values, err := readConfigFile(configFile)
+		if err != nil {
+			return cfg, nil, err
+		}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			if fs.Lookup(name) == nil || name == "config" {
+				return cfg, nil, fmt.Errorf("%s: unknown setting %q", configFile, name)
+			}
+			if sources[name] == "flag" {
+				continue
+			}
+			err = fs.Set(name, values[name])
+			if err != nil {
+				return cfg, nil, fmt.Errorf("%s: invalid value %q for %s: %w", configFile, values[name], name, err)
+			}
+			sources[name] = "file"
+		}
+	}
+
+	var envErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := lookupEnv(envName(f.Name))
+		if !ok || envErr != nil || sources[f.Name] == "flag" || f.Name == "config" {
+			return
+		}
+		err := fs.Set(f.Name, value)
+		if err != nil {
+			envErr = fmt.Errorf("invalid value %q for %s: %w", value, envName(f.Name), err)
+			return
+		}
+		sources[f.Name] = "env"
+	})
+	if envErr != nil {
+		return cfg, nil, envErr
+	}
+
+	if cfg.log.level == "" {
+		cfg.log.level = "info"
+		if cfg.env == "development" {
+			cfg.log.level = "debug"
+		}
+	}
+
+	v := validator.New()
+	if validateConfig(v, cfg); !v.Valid() {
+		var problems []string
+		for _, name := range slices.Sorted(maps.Keys(v.Errors)) {
+			problems = append(problems, fmt.Sprintf("%s %s", name, v.Errors[name]))
+		}
+		return cfg, nil, fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	var settings []configSetting
+	fs.VisitAll(func(f *flag.Flag) {
+		if source, ok := sources[f.Name]; ok {
+			settings = append(settings, configSetting{
+				name:   f.Name,
+				value:  redactSetting(f.Name, f.Value.String()),
+				source: source,
+			})
+		}
+	})
+	return cfg, settings, nil
+}
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// readConfigFile returns the settings in a YAML file by flag name, flattening
+// nested keys and joining lists with commas.
+func readConfigFile(path string) (map[string]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	err = yaml.Unmarshal(contents, &tree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	var flatten func(prefix string, tree map[string]any) error
+	flatten = func(prefix string, tree map[string]any) error {
+		for key, value := range tree {
+			name := prefix + strings.ReplaceAll(key, "_", "-")
+			switch value := value.(type) {
+			case map[string]any:
+				err := flatten(name+"-", value)
+				if err != nil {
+					return err
+				}
+			case []any:
+				items := make([]string, len(value))
+				for i, item := range value {
+					items[i] = fmt.Sprint(item)
+				}
+				values[name] = strings.Join(items, ",")
+			case nil:
+				return fmt.Errorf("%s: %s has no value", path, name)
+			default:
+				values[name] = fmt.Sprint(value)
+			}
+		}
+		return nil
+	}
+	return values, flatten("", tree)
+}
+
+var dsnPasswordRX = regexp.MustCompile(`password\s*=\s*('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactSetting hides secrets, keeping enough of a DSN to tell which database
+// it's for.
+func redactSetting(name, value string) string {
+	if !slices.Contains(secretSettings, name) || value == "" {
+		return value
+	}
+	if name == "db-dsn" {
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" {
+			return u.Redacted()
+		}
+		if !strings.Contains(value, "://") {
+			return dsnPasswordRX.ReplaceAllString(value, "password=xxxxx")
+		}
+	}
+	return "xxxxx"
+}
+
+func validateConfig(v *validator.Validator, cfg config) {
+	v.Check(cfg.port > 0 && cfg.port <= 65535, "port", "must be between 1 and 65535")
+	v.Check(cfg.env != "", "env", "must be provided")
+	v.Check(cfg.shutdownTimeout > 0, "shutdown-timeout", "must be positive")
+	v.Check(cfg.server.idleTimeout > 0, "idle-timeout", "must be positive")
+	v.Check(cfg.server.readTimeout > 0, "read-timeout", "must be positive")
+	v.Check(cfg.server.writeTimeout > 0, "write-timeout", "must be positive")
+	v.Check(cfg.maxRequestBody > 0, "max-request-body", "must be positive")
+	v.Check(cfg.defaultPageSize > 0 && cfg.defaultPageSize <= 500, "default-page-size", "must be between 1 and 500")
+	v.Check(cfg.authTokenTTL > 0, "auth-token-ttl", "must be positive")
+
+	v.Check(cfg.db.DSN != "" || cfg.inMemory, "db-dsn", "must be provided unless running in memory")
+	v.Check(cfg.db.MaxOpenConns > 0, "db-max-open-conns", "must be positive")
+	v.Check(cfg.db.MinConns >= 0 && cfg.db.MinConns <= cfg.db.MaxOpenConns, "db-min-conns", "must be between 0 and db-max-open-conns")
+	v.Check(cfg.db.MaxIdleTime > 0, "db-max-idle-time", "must be positive")
+	v.Check(cfg.db.QueryTimeout > 0, "db-query-timeout", "must be positive")
+	v.Check(cfg.db.BulkTimeout > 0, "db-bulk-timeout", "must be positive")
+	v.Check(validator.PermittedValue(cfg.dbMigrations, "check", "auto", "ignore"), "db-migrations", "must be check, auto or ignore")
+
+	if cfg.limiter.enabled {
+		v.Check(cfg.limiter.rps > 0, "limiter-rps", "must be positive")
+		v.Check(cfg.limiter.burst > 0, "limiter-burst", "must be positive")
+	}
+
+	for _, origin := range cfg.cors.trustedOrigins {
+		v.Check(validator.ValidURL(origin), "cors-trusted-origins", "must be URLs")
+	}
+	v.Check(cfg.metrics.port >= 0 && cfg.metrics.port <= 65535, "metrics-port", "must be between 0 and 65535")
+	v.Check(cfg.metrics.port == 0 || cfg.metrics.port != cfg.port, "metrics-port", "must be different from port")
+
+	v.Check(validator.PermittedValue(cfg.tracing.exporter, "none", "stdout", "otlp"), "trace-exporter", "must be none, stdout or otlp")
+	v.Check(cfg.tracing.sampleRatio >= 0 && cfg.tracing.sampleRatio <= 1, "trace-sample-ratio", "must be between 0 and 1")
+
+	var level slog.Level
+	v.Check(validator.PermittedValue(cfg.log.format, "text", "json"), "log-format", "must be text or json")
+	v.Check(level.UnmarshalText([]byte(cfg.log.level)) == nil, "log-level", "must be debug, info, warn or error")
+
+	v.Check(validator.ValidURL(cfg.webBaseURL), "base-url", "must be a URL")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/ggetzie/badwords_be/internal/data"
@@ -9,7 +10,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey       = contextKey("user")
+	requestLogContextKey = contextKey("requestLog")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -23,3 +27,33 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+// requestLog holds the logger for a request. It's shared by pointer so that
+// authenticate can add the user to the logger used by logRequest, which is
+// further out in the middleware chain.
+type requestLog struct {
+	logger *slog.Logger
+}
+
+func (app *application) contextSetRequestLog(r *http.Request, log *requestLog) *http.Request {
+	ctx := context.WithValue(r.Context(), requestLogContextKey, log)
+	return r.WithContext(ctx)
+}
+
+// requestLogger returns the logger for a request, which includes its request
+// ID, or the application's logger outside of a request.
+func (app *application) requestLogger(r *http.Request) *slog.Logger {
+	log, ok := r.Context().Value(requestLogContextKey).(*requestLog)
+	if !ok {
+		return app.logger
+	}
+	return log.logger
+}
+
+// addRequestLogAttrs adds attributes to every later log entry for the request.
+func (app *application) addRequestLogAttrs(r *http.Request, args ...any) {
+	log, ok := r.Context().Value(requestLogContextKey).(*requestLog)
+	if ok {
+		log.logger = log.logger.With(args...)
+	}
+}
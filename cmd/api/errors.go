@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime/debug"
@@ -12,11 +14,17 @@ func (app *application) logError(r *http.Request, err error) {
 		uri    = r.URL.RequestURI()
 		trace  = fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
 	)
-	app.logger.Error(err.Error(), "method", method, "uri", uri)
+	logger := app.requestLogger(r)
+	// a query cancelled because the client went away isn't a server error
+	if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+		logger.Info("request cancelled", "method", method, "uri", uri)
+		return
+	}
+	logger.Error(err.Error(), "method", method, "uri", uri)
 
 	// log stack trace in development
 	if app.config.env == "development" {
-		app.logger.Debug(trace)
+		logger.Debug(trace)
 	}
 }
 
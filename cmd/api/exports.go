@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/puzzlefile"
+	"github.com/ggetzie/badwords_be/internal/validator"
+)
+
+func (app *application) exportPuzzlesHandler(w http.ResponseWriter, r *http.Request) {
+	var filter data.PuzzleExportFilter
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	format := app.readString(qs, "format", puzzlefile.FormatNDJSON)
+	filter.AuthorID = app.readInt(qs, "author", 0, v)
+	filter.CreatedAfter = app.readDateTime(qs, "created_after", time.Time{}, v)
+	filter.CreatedBefore = app.readDateTime(qs, "created_before", time.Time{}, v)
+
+	v.Check(validator.PermittedValue(format, puzzlefile.ExportFormats...), "format", "must be ndjson or ipuz")
+	v.Check(filter.AuthorID >= 0, "author", "must be a positive integer")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ew, err := puzzlefile.NewExportWriter(w, format)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// an export can take much longer than an ordinary response
+	err = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("puzzles-%s%s", time.Now().UTC().Format("20060102-150405"), ew.Extension())
+	w.Header().Set("Content-Type", ew.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// once the first puzzle has been written the status can't change, so a
+	// failure part way through can only be logged. A zip export is left
+	// without its directory, so the truncated archive won't open.
+	err = app.models.Puzzles.Export(r.Context(), filter, ew.Write)
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+	err = ew.Close()
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/autofill"
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/validator"
+	"github.com/ggetzie/badwords_be/internal/wordlist"
+)
+
+const (
+	defaultFillTimeout = 3 * time.Second
+	// maxFillTimeout keeps the search inside the server's write timeout.
+	maxFillTimeout = 8 * time.Second
+	maxFillResults = 10
+)
+
+func (app *application) fillGridHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Width      int             `json:"width"`
+		Height     int             `json:"height"`
+		Content    data.PuzzleData `json:"content"`
+		ListID     int             `json:"list_id"`
+		MinScore   int             `json:"min_score"`
+		MaxResults int             `json:"max_results"`
+		TimeoutMS  int             `json:"timeout_ms"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.MaxResults == 0 {
+		input.MaxResults = 1
+	}
+	timeout := defaultFillTimeout
+	if input.TimeoutMS != 0 {
+		timeout = time.Duration(input.TimeoutMS) * time.Millisecond
+	}
+
+	v := validator.New()
+	v.Check(input.Width > 0 && input.Width <= 25, "width", "must be between 1 and 25")
+	v.Check(input.Height > 0 && input.Height <= 25, "height", "must be between 1 and 25")
+	v.Check(input.ListID >= 0, "list_id", "must be a positive integer")
+	v.Check(wordlist.ValidScore(input.MinScore), "min_score", "must be between 0 and 100")
+	v.Check(input.MaxResults > 0 && input.MaxResults <= maxFillResults, "max_results", "must be between 1 and 10")
+	v.Check(timeout > 0 && timeout <= maxFillTimeout, "timeout_ms", "must be between 1 and 8000")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	g, err := input.Content.Grid(input.Width, input.Height)
+	if err != nil {
+		v.AddError("content", err.Error())
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if input.ListID != 0 {
+		list, err := app.models.WordLists.GetByID(r.Context(), input.ListID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if list == nil || !list.CanRead(user) {
+			v.AddError("list_id", "must be the house list or one of your own lists")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	// only words that could fit one of the grid's entries are loaded
+	var lengths []int
+	seen := make(map[int]bool)
+	for _, entry := range g.Entries() {
+		if !seen[entry.Length] {
+			seen[entry.Length] = true
+			lengths = append(lengths, entry.Length)
+		}
+	}
+
+	entries, err := app.models.WordLists.GetForFill(r.Context(), user.ID, input.ListID, input.MinScore, lengths)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	words := make([]autofill.Word, len(entries))
+	for i, entry := range entries {
+		words[i] = autofill.Word{Text: entry.Word, Score: entry.Score}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	results, err := autofill.Fill(ctx, g, autofill.NewDictionary(words), autofill.Options{MaxResults: input.MaxResults})
+	if err != nil {
+		switch {
+		case errors.Is(err, autofill.ErrNoFill):
+			app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, context.DeadlineExceeded):
+			app.errorResponse(w, r, http.StatusUnprocessableEntity, "no fill was found within the time limit")
+		case errors.Is(err, context.Canceled):
+			// the client has gone away, so there's no one to respond to
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	fills := make([]envelope, len(results))
+	for i, result := range results {
+		fills[i] = envelope{
+			"score":   result.Score,
+			"grid":    result.Grid.Rows(),
+			"content": input.Content.WithAnswers(result.Grid),
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"fills":      fills,
+		"words":      len(words),
+		"elapsed_ms": time.Since(start).Milliseconds(),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
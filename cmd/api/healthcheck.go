@@ -1,16 +1,106 @@
 package main
 
-import "net/http"
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+)
 
 func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
 	env := envelope{"status": "available",
-		"system_info": map[string]string{
-			"environment": app.config.env,
-			"version":     version,
-		}}
+		"system_info": app.systemInfo(),
+	}
 	err := app.writeJSON(w, http.StatusOK, env, nil)
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// livenessHandler reports that the process is up and serving requests.
+// Failing it should get the instance restarted, so it doesn't depend on
+// anything else.
+func (app *application) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, http.StatusOK, envelope{"status": "alive"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readinessHandler reports whether the instance can handle requests, i.e. its
+// database is reachable and migrated to the version the code expects. It
+// responds 503 if not so load balancers stop sending it traffic.
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	checks := envelope{}
+	ready := true
+
+	if app.db == nil {
+		checks["database"] = envelope{"status": "skipped", "reason": "running in memory"}
+	} else {
+		database := envelope{"status": "ok"}
+		err := app.db.Ping(ctx)
+		if err != nil {
+			app.requestLogger(r).Error(err.Error(), "check", "database")
+			database["status"] = "unavailable"
+			ready = false
+		}
+		checks["database"] = database
+
+		migrations := envelope{"status": "ok", "expected": data.SchemaVersion}
+		version, dirty, err := data.MigrationVersion(ctx, app.db)
+		switch {
+		case err != nil:
+			app.requestLogger(r).Error(err.Error(), "check", "migrations")
+			migrations["status"] = "unavailable"
+			ready = false
+		case dirty || version != data.SchemaVersion:
+			migrations["status"] = "mismatch"
+			migrations["version"] = version
+			migrations["dirty"] = dirty
+			ready = false
+		default:
+			migrations["version"] = version
+		}
+		checks["migrations"] = migrations
+	}
+
+	env := envelope{"status": "ready", "checks": checks, "system_info": app.systemInfo()}
+	status := http.StatusOK
+	if !ready {
+		env["status"] = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	err := app.writeJSON(w, status, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// systemInfo describes the running build. Without ldflags the commit falls
+// back to the revision Go embeds when building from a checkout.
+func (app *application) systemInfo() map[string]string {
+	info := map[string]string{
+		"environment": app.config.env,
+		"version":     version,
+		"commit":      commit,
+		"build_time":  cmp.Or(buildTime, "unknown"),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info["go_version"] = build.GoVersion
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" && info["commit"] == "" {
+				info["commit"] = setting.Value
+			}
+		}
+	}
+	info["commit"] = cmp.Or(info["commit"], "unknown")
+	return info
+}
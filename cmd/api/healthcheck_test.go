@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestHealthcheck(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	code, response := ts.do(t, http.MethodGet, "/v1/healthcheck", "", nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	if got := field(t, response, "status"); got != "available" {
+		t.Errorf("got status %q; want %q", got, "available")
+	}
+	if got := field(t, response, "system_info", "environment"); got != "testing" {
+		t.Errorf("got environment %q; want %q", got, "testing")
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	code, response := ts.do(t, http.MethodGet, "/v1/healthcheck/live", "", nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	if got := field(t, response, "status"); got != "alive" {
+		t.Errorf("got status %q; want %q", got, "alive")
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("In memory", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+
+		code, response := ts.do(t, http.MethodGet, "/v1/healthcheck/ready", "", nil)
+		if code != http.StatusOK {
+			t.Fatalf("got status %d; want %d", code, http.StatusOK)
+		}
+		if got := field(t, response, "checks", "database", "status"); got != "skipped" {
+			t.Errorf("got database status %q; want %q", got, "skipped")
+		}
+		if got := field(t, response, "system_info", "commit"); got == "" {
+			t.Error("no commit in the system info")
+		}
+	})
+
+	t.Run("Database unreachable", func(t *testing.T) {
+		app := newTestApplication(t)
+		// the pool connects lazily, so this fails on the first ping
+		pool, err := pgxpool.New(context.Background(), "postgres://badwords@127.0.0.1:1/badwords?connect_timeout=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(pool.Close)
+		app.db = pool
+		ts := newTestServer(t, app.routes())
+
+		code, response := ts.do(t, http.MethodGet, "/v1/healthcheck/ready", "", nil)
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("got status %d; want %d", code, http.StatusServiceUnavailable)
+		}
+		if got := field(t, response, "checks", "database", "status"); got != "unavailable" {
+			t.Errorf("got database status %q; want %q", got, "unavailable")
+		}
+		if got := field(t, response, "checks", "migrations", "status"); got != "unavailable" {
+			t.Errorf("got migrations status %q; want %q", got, "unavailable")
+		}
+		if got := field(t, response, "status"); got != "unavailable" {
+			t.Errorf("got status %q; want %q", got, "unavailable")
+		}
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	ts.do(t, http.MethodGet, "/v1/puzzles/1", "", nil)
+	ts.do(t, "BREW", "/v1/puzzles/1", "", nil)
+
+	rs, err := ts.Client().Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`badwords_http_requests_total{method="GET",route="/v1/puzzles/:id",status="404"} 1`,
+		`badwords_http_requests_total{method="other",route="unmatched",status="405"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics don't contain %q", want)
+		}
+	}
+}
@@ -2,18 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ggetzie/badwords_be/internal/validator"
 	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type envelope map[string]any
@@ -36,16 +39,15 @@ func (app *application) writeJSON(
 
 func (app *application) readJSON(
 	w http.ResponseWriter, r *http.Request, dst any) error {
-	maxBytes := 1_048_576
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+	maxBytes := app.config.maxRequestBody
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 	if app.config.env == "development" {
 		bodyBytes, err := io.ReadAll(r.Body)
 		if err != nil {
 			return err
 		}
 		bodyStr := string(bodyBytes)
-		app.logger.Debug(fmt.Sprintf("Body length: %d", len(bodyStr)))
-		app.logger.Debug(fmt.Sprintf("Reading JSON body: %s", bodyStr))
+		app.requestLogger(r).Debug("reading JSON body", "length", len(bodyStr), "body", bodyStr)
 		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 	}
 	dec := json.NewDecoder(r.Body)
@@ -95,6 +97,14 @@ func (app *application) readString(qs url.Values, key, defaultValue string) stri
 	return s
 }
 
+func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	return strings.Split(s, ",")
+}
+
 func (app *application) readDateTime(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
 	s := qs.Get(key)
 	if s == "" {
@@ -121,6 +131,19 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
 func (app *application) readIntList(qs url.Values, key string, defaultValue []int, v *validator.Validator, minValue, maxValue int) []int {
 	var nums []int
 	s := qs.Get(key)
@@ -143,6 +166,28 @@ func (app *application) readIntList(qs url.Values, key string, defaultValue []in
 	return nums
 }
 
+func (app *application) readSize(qs url.Values, key string, v *validator.Validator) (width, height int) {
+	s := qs.Get(key)
+	if s == "" {
+		return 0, 0
+	}
+	w, h, found := strings.Cut(strings.ToLower(s), "x")
+	if !found {
+		h = w
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil {
+		v.AddError(key, "must be a size such as 15 or 15x15")
+		return 0, 0
+	}
+	height, err = strconv.Atoi(h)
+	if err != nil {
+		v.AddError(key, "must be a size such as 15 or 15x15")
+		return 0, 0
+	}
+	return width, height
+}
+
 func (app *application) readStringParam(r *http.Request, key string) (string, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 	value := params.ByName(key)
@@ -173,15 +218,22 @@ func (app *application) readIDParam(r *http.Request) (int, error) {
 	return id, nil
 }
 
-func (app *application) background(fn func()) {
+func (app *application) background(r *http.Request, fn func(ctx context.Context)) {
+	// the task outlives the request, so its context comes from the
+	// application, but it's traced as part of the request
+	ctx := trace.ContextWithSpanContext(app.ctx, trace.SpanContextFromContext(r.Context()))
+	logger := app.requestLogger(r)
+
 	app.wg.Add(1)
+	app.metrics.background.Inc()
 	go func() {
 		defer app.wg.Done()
+		defer app.metrics.background.Dec()
 		defer func() {
 			if err := recover(); err != nil {
-				app.logger.Error(fmt.Sprintf("panic: %v", err))
+				logger.Error(fmt.Sprintf("panic: %v", err), "stack", string(debug.Stack()))
 			}
 		}()
-		fn()
+		fn(ctx)
 	}()
 }
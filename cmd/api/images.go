@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/render"
+	"github.com/ggetzie/badwords_be/internal/validator"
+)
+
+// maxSolveSeconds is the longest solve time a share card will show, 99:59:59.
+const maxSolveSeconds = 99*3600 + 59*60 + 59
+
+func (app *application) gridSVGHandler(w http.ResponseWriter, r *http.Request) {
+	app.gridImage(w, r, "image/svg+xml", render.SVG)
+}
+
+func (app *application) gridPNGHandler(w http.ResponseWriter, r *http.Request) {
+	app.gridImage(w, r, "image/png", render.PNG)
+}
+
+// canSeeSolution reports whether the viewer may see a puzzle's answers: only
+// its author, editors and superusers can.
+func (app *application) canSeeSolution(r *http.Request, puzzle *data.Puzzle, permissions data.Permissions) bool {
+	user := app.contextGetUser(r)
+	isAuthor := !user.IsAnonymous() && puzzle.Author.ID == user.ID
+	return isAuthor || permissions.Include(data.Superuser) || permissions.Include(data.PuzzlesUpdate)
+}
+
+// gridImage serves the empty grid of a puzzle, or its solution to the author
+// and to editors.
+func (app *application) gridImage(w http.ResponseWriter, r *http.Request, contentType string, draw func(io.Writer, render.Puzzle, bool) error) {
+	v := validator.New()
+	solution := app.readBool(r.URL.Query(), "solution", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	puzzle, permissions, ok := app.readVisiblePuzzle(w, r)
+	if !ok {
+		return
+	}
+
+	if solution && !app.canSeeSolution(r, puzzle, permissions) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	p, err := renderPuzzle(puzzle)
+	if err != nil {
+		app.renderErrorResponse(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = draw(&buf, p, solution)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.writeImage(w, contentType, puzzle.Published && !solution, buf.Bytes())
+}
+
+func (app *application) shareCardHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	seconds := app.readInt(r.URL.Query(), "time", 0, v)
+	v.Check(seconds > 0 && seconds <= maxSolveSeconds, "time", "must be a number of seconds between 1 and 359999")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	puzzle, _, ok := app.readVisiblePuzzle(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := renderPuzzle(puzzle)
+	if err != nil {
+		app.renderErrorResponse(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = render.ShareCard(&buf, p, seconds)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.writeImage(w, "image/png", puzzle.Published, buf.Bytes())
+}
+
+// writeImage sends a rendered image. Images of published puzzles may be cached
+// by the link previewers that fetch them; anything else is private.
+func (app *application) writeImage(w http.ResponseWriter, contentType string, public bool, image []byte) {
+	w.Header().Set("Content-Type", contentType)
+	if public {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	w.Write(image)
+}
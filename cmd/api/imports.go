@@ -0,0 +1,295 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"runtime/debug"
+	"strings"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/puzzlefile"
+	"github.com/ggetzie/badwords_be/internal/validator"
+)
+
+const (
+	maxImportBytes     = 50 << 20
+	maxImportFileBytes = 1 << 20
+	maxImportFiles     = 1000
+	// importSaveEvery sets how often progress is saved while an import runs.
+	importSaveEvery = 25
+)
+
+func (app *application) createImportHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	dryRun := app.readBool(r.URL.Query(), "dry_run", false, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	archive, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxImportBytes))
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("body must be a zip archive"))
+		return
+	}
+	files := importFiles(zr)
+	v.Check(len(files) > 0, "archive", "must contain at least one file")
+	v.Check(len(files) <= maxImportFiles, "archive", fmt.Sprintf("must not contain more than %d files", maxImportFiles))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	sum := sha256.Sum256(archive)
+	user := app.contextGetUser(r)
+	imp := &data.Import{
+		OwnerID:   user.ID,
+		Checksum:  hex.EncodeToString(sum[:]),
+		DryRun:    dryRun,
+		FileCount: len(files),
+	}
+
+	headers := make(http.Header)
+
+	// uploading the same archive again returns the import it already started
+	if !dryRun {
+		existing, err := app.models.Imports.GetByChecksum(r.Context(), user.ID, imp.Checksum)
+		switch {
+		case err == nil:
+			headers.Set("Location", fmt.Sprintf("/v1/imports/%d", existing.ID))
+			err = app.writeJSON(w, http.StatusOK, envelope{"import": existing}, headers)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		case !errors.Is(err, data.ErrRecordNotFound):
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.models.Imports.Insert(r.Context(), imp)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	job := *imp
+	author := *user
+	app.background(r, func(ctx context.Context) {
+		app.runImport(ctx, &job, files, author)
+	})
+
+	headers.Set("Location", fmt.Sprintf("/v1/imports/%d", imp.ID))
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"import": imp}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getImportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	imp, err := app.models.Imports.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if imp.OwnerID != user.ID {
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !permissions.Include(data.Superuser) {
+			app.notFoundResponse(w, r)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"import": imp}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// importFiles lists the files in an archive, leaving out directories and the
+// hidden files that archivers add.
+func importFiles(zr *zip.Reader) []*zip.File {
+	var files []*zip.File
+	for _, f := range zr.File {
+		base := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// runImport works through the files of an import, saving its progress as it
+// goes. A database error or a panic stops the import and marks it failed;
+// problems with individual files are recorded in their results.
+func (app *application) runImport(ctx context.Context, imp *data.Import, files []*zip.File, author data.User) {
+	// the final status is saved even when shutdown has cancelled ctx, so the
+	// import isn't left running
+	saveCtx := context.WithoutCancel(ctx)
+
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("panic: %v", err), "import_id", imp.ID, "stack", string(debug.Stack()))
+			imp.Status = data.ImportFailed
+			imp.Error = fmt.Sprintf("the import stopped after %d files because of a server error", imp.Processed)
+			err := app.models.Imports.Update(saveCtx, imp)
+			if err != nil {
+				app.logger.Error(err.Error(), "import_id", imp.ID)
+			}
+		}
+	}()
+
+	imp.Status = data.ImportRunning
+	err := app.models.Imports.Update(ctx, imp)
+	if err != nil {
+		app.logger.Error(err.Error(), "import_id", imp.ID)
+		return
+	}
+
+	for _, f := range files {
+		result, err := app.importFile(ctx, f, author, imp.DryRun)
+		if err != nil {
+			app.logger.Error(err.Error(), "import_id", imp.ID, "file", f.Name)
+			imp.Status = data.ImportFailed
+			imp.Error = fmt.Sprintf("the import stopped at %s because of a server error", f.Name)
+			if ctx.Err() != nil {
+				imp.Error = fmt.Sprintf("the import stopped at %s because the server shut down", f.Name)
+			}
+			break
+		}
+		imp.Results = append(imp.Results, result)
+		imp.Processed++
+		if imp.Processed%importSaveEvery == 0 {
+			err = app.models.Imports.Update(ctx, imp)
+			if err != nil {
+				app.logger.Error(err.Error(), "import_id", imp.ID)
+			}
+		}
+	}
+
+	if imp.Status == data.ImportRunning {
+		imp.Status = data.ImportDone
+	}
+	err = app.models.Imports.Update(saveCtx, imp)
+	if err != nil {
+		app.logger.Error(err.Error(), "import_id", imp.ID)
+	}
+}
+
+// importFile reads, validates and, unless it's a dry run, saves the puzzle in
+// one file. Imported puzzles are unpublished drafts. A file already imported
+// by the same author is reported as a duplicate rather than copied.
+func (app *application) importFile(ctx context.Context, f *zip.File, author data.User, dryRun bool) (data.ImportResult, error) {
+	result := data.ImportResult{File: f.Name}
+	invalid := func(message string) (data.ImportResult, error) {
+		result.Status = data.ImportFileInvalid
+		result.Errors = map[string]string{"file": message}
+		return result, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return invalid(err.Error())
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, maxImportFileBytes+1))
+	if err != nil {
+		return invalid(err.Error())
+	}
+	if len(b) > maxImportFileBytes {
+		return invalid(fmt.Sprintf("must not be larger than %d bytes", maxImportFileBytes))
+	}
+
+	puzzle, err := puzzlefile.Read(f.Name, b)
+	if err != nil {
+		if errors.Is(err, puzzlefile.ErrUnsupported) {
+			result.Status = data.ImportFileSkipped
+			result.Errors = map[string]string{"file": "must be a .json, .ipuz or .puz file"}
+			return result, nil
+		}
+		return invalid(err.Error())
+	}
+	sum := sha256.Sum256(b)
+	puzzle.SourceHash = hex.EncodeToString(sum[:])
+	puzzle.Author = author
+	if puzzle.Description == "" {
+		puzzle.Description = "Imported from " + path.Base(f.Name)
+	}
+	result.Title = puzzle.Title
+
+	v := validator.New()
+	data.ValidatePuzzle(v, puzzle)
+	data.ValidatePuzzleContent(v, puzzle.Content)
+	if !v.Valid() {
+		result.Status = data.ImportFileInvalid
+		result.Errors = v.Errors
+		return result, nil
+	}
+
+	id, err := app.models.Puzzles.GetIDBySourceHash(ctx, author.ID, puzzle.SourceHash)
+	switch {
+	case err == nil:
+		result.Status = data.ImportFileDuplicate
+		result.PuzzleID = id
+		return result, nil
+	case !errors.Is(err, data.ErrRecordNotFound):
+		return result, err
+	}
+
+	if dryRun {
+		result.Status = data.ImportFileValid
+		return result, nil
+	}
+
+	err = app.models.Puzzles.Insert(ctx, puzzle)
+	if err != nil {
+		if errors.Is(err, data.ErrDuplicatePuzzle) {
+			result.Status = data.ImportFileDuplicate
+			return result, nil
+		}
+		return result, err
+	}
+	result.Status = data.ImportFileCreated
+	result.PuzzleID = puzzle.ID
+	return result, nil
+}
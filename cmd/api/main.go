@@ -1,109 +1,168 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/ggetzie/badwords_be/internal/audit"
 	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-const version = "1.0.0"
-
-type config struct {
-	port       int
-	env        string
-	db         data.DBConfig
-	webBaseURL string
-
-	limiter struct {
-		rps     float64
-		burst   int
-		enabled bool
-	}
-
-	defaultPageSize int
-
-	cors struct {
-		trustedOrigins []string
-	}
-}
+// commit and buildTime are set when building with
+// -ldflags "-X main.commit=... -X main.buildTime=...", see the Makefile.
+var (
+	version   = "1.0.0"
+	commit    string
+	buildTime string
+)
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
-	wg     sync.WaitGroup
+	config  config
+	logger  *slog.Logger
+	db      *pgxpool.Pool
+	models  data.Models
+	audit   *audit.Recorder
+	metrics *metrics
+	wg      sync.WaitGroup
+
+	// ctx is the parent of every request's context and of background tasks.
+	// It's cancelled if they haven't finished by the end of the shutdown
+	// timeout, which stops their queries.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func main() {
-	var cfg config
-	flag.IntVar(&cfg.port, "port", 8000, "Server port to listen on")
-	flag.StringVar(&cfg.env, "env", "development", "Application environment (development|production)")
-
-	// database connection pool settings
-	flag.StringVar(&cfg.db.DSN, "db-dsn", "", "PostgreSQL DSN")
-	flag.IntVar(&cfg.db.MaxOpenConns, "db-max-open-conns", 25, "PostgreSQL database connection pool max open connections")
-	flag.IntVar(&cfg.db.MinConns, "db-min-conns", 4, "PostgreSQL database connection pool minimum connections")
-	flag.DurationVar(&cfg.db.MaxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL database connection pool max connection idle time")
-
-	// rate limiter settings
-	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter maximum requests per second")
-	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter burst")
-	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", false, "Enable rate limiter")
-
-	// AWS Settings
-	var AWS_ACCESS_KEY_ID string
-	var AWS_SECRET_ACCESS_KEY string
-	flag.StringVar(&AWS_ACCESS_KEY_ID, "aws-access-key-id", "", "AWS Access Key ID")
-	flag.StringVar(&AWS_SECRET_ACCESS_KEY, "aws-secret-access-key", "", "AWS Secret Access Key")
-
-	// CORS settings
-	flag.Func("cors-trusted-origins", "List of trusted origins for CORS (comma separated)", func(val string) error {
-		cfg.cors.trustedOrigins = strings.Split(val, ",")
-		return nil
-	})
-
-	// Base URL - the hostname for the web frontend to build links
-	flag.StringVar(&cfg.webBaseURL, "base-url", "http://localhost:3001", "Base URL for the web frontend")
-
-	flag.Parse()
-
-	var logOptions slog.HandlerOptions
-
-	if cfg.env == "development" {
-		logOptions = slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		}
+	cfg, settings, err := loadConfig(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	cfg.defaultPageSize = 20
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &logOptions))
+	logger, err := newLogger(os.Stdout, cfg.log.format, cfg.log.level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	for _, origin := range cfg.cors.trustedOrigins {
-		logger.Info("cors setting", "trusted_origin", origin)
+	for _, setting := range settings {
+		logger.Info("config setting", "name", setting.name, "value", setting.value, "source", setting.source)
 	}
-	logger.Info("web base url", "url", cfg.webBaseURL)
-	dbpool, err := data.OpenDB(cfg.db)
+
+	shutdownTracing, err := setupTracing(cfg)
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
-	logger.Info("database connection pool established")
+	var dbpool *pgxpool.Pool
+	var models data.Models
+	if cfg.inMemory {
+		logger.Warn("using in-memory storage, everything will be lost when the server stops")
+		models = data.NewMemoryModels()
+	} else {
+		dbpool, err = data.OpenDB(cfg.db)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		logger.Info("database connection pool established")
+
+		err = migrateDB(context.Background(), dbpool, cfg.dbMigrations, logger)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		models = data.NewModels(dbpool, cfg.db.QueryTimeout, cfg.db.BulkTimeout)
+	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(dbpool),
+		config:  cfg,
+		logger:  logger,
+		db:      dbpool,
+		models:  models,
+		audit:   audit.New(models.Audit, logger),
+		metrics: newMetrics(dbpool),
+		ctx:     ctx,
+		cancel:  cancel,
 	}
 
 	err = app.serve()
+
+	// flush the spans of the last requests before exiting
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if shutdownErr := shutdownTracing(flushCtx); shutdownErr != nil {
+		logger.Error(shutdownErr.Error())
+	}
+
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
 
 }
+
+// newLogger returns a logger writing in the given format that discards
+// entries below the given level.
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var minLevel slog.Level
+	err := minLevel.UnmarshalText([]byte(level))
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	options := &slog.HandlerOptions{Level: minLevel}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// migrateDB applies pending migrations when mode is auto and then, unless
+// mode is ignore, makes sure the database is at the version the code expects.
+func migrateDB(ctx context.Context, db *pgxpool.Pool, mode string, logger *slog.Logger) error {
+	switch mode {
+	case "ignore":
+		return nil
+	case "auto":
+		applied, err := data.MigrateUp(ctx, db)
+		for _, m := range applied {
+			logger.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			return err
+		}
+	case "check":
+	default:
+		return fmt.Errorf("unknown -db-migrations mode %q", mode)
+	}
+
+	version, dirty, err := data.MigrationVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database migration %d failed partway through, fix it and run bw_migrate force", version)
+	}
+	if version != data.SchemaVersion {
+		return fmt.Errorf("database is at migration %d but this build expects %d, run bw_migrate up or start with -db-migrations=auto", version, data.SchemaVersion)
+	}
+	return nil
+}
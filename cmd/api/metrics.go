@@ -0,0 +1,182 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type metrics struct {
+	registry *prometheus.Registry
+	// router is used to label requests with the route they matched rather
+	// than their path, which would give a series per puzzle
+	router *httprouter.Router
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	rateLimited     prometheus.Counter
+	background      prometheus.Gauge
+	logins          *prometheus.CounterVec
+	publishes       prometheus.Counter
+}
+
+func newMetrics(dbpool *pgxpool.Pool) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "badwords_http_requests_total",
+			Help: "HTTP requests by route and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "badwords_http_request_duration_seconds",
+			Help:    "HTTP request latency by route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		rateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "badwords_rate_limited_requests_total",
+			Help: "Requests rejected by the rate limiter.",
+		}),
+		background: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "badwords_background_tasks",
+			Help: "Background tasks currently running.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "badwords_logins_total",
+			Help: "Login attempts by result.",
+		}, []string{"result"}),
+		publishes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "badwords_puzzle_publishes_total",
+			Help: "Puzzles published.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.rateLimited,
+		m.background,
+		m.logins,
+		m.publishes,
+	)
+	if dbpool != nil {
+		m.registry.MustRegister(newPoolCollector(dbpool))
+	}
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// route returns the pattern of the route a request matches, e.g.
+// /v1/puzzles/:id, or "unmatched" for requests that don't match one.
+func (m *metrics) route(r *http.Request) string {
+	if m.router == nil {
+		return "unmatched"
+	}
+	handle, params, _ := m.router.Lookup(r.Method, r.URL.Path)
+	if handle == nil {
+		return "unmatched"
+	}
+
+	// put the parameter names back in place of their values, in order
+	segments := strings.Split(r.URL.Path, "/")
+	i := 0
+	for _, p := range params {
+		for ; i < len(segments); i++ {
+			if segments[i] == p.Value {
+				segments[i] = ":" + p.Key
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// routedMethods are the methods the router serves or answers itself. Any
+// other method is labelled "other" so clients can't create a series each.
+var routedMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+func (m *metrics) observeRequest(r *http.Request, status int, seconds float64) {
+	method := r.Method
+	if !routedMethods[method] {
+		method = "other"
+	}
+	labels := prometheus.Labels{
+		"method": method,
+		"route":  m.route(r),
+		"status": strconv.Itoa(status),
+	}
+	m.requests.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(seconds)
+}
+
+// poolCollector reports the connection pool's statistics when scraped.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	acquireWait     *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("badwords_db_pool_"+name, help, nil, nil)
+	}
+	return &poolCollector{
+		pool:            pool,
+		acquiredConns:   desc("acquired_conns", "Connections currently in use."),
+		idleConns:       desc("idle_conns", "Idle connections in the pool."),
+		totalConns:      desc("total_conns", "Connections in the pool."),
+		maxConns:        desc("max_conns", "Maximum size of the pool."),
+		acquireCount:    desc("acquires_total", "Connections acquired from the pool."),
+		acquireWait:     desc("acquire_wait_seconds_total", "Time spent waiting for a connection because the pool was empty."),
+		emptyAcquires:   desc("empty_acquires_total", "Acquires that had to wait because the pool was empty."),
+		canceledAcquire: desc("canceled_acquires_total", "Acquires canceled by their context."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireWait
+	ch <- c.emptyAcquires
+	ch <- c.canceledAcquire
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, s.EmptyAcquireWaitTime().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/rand"
 	"fmt"
 	"net/http"
 	"runtime/debug"
@@ -11,15 +12,49 @@ import (
 	"github.com/ggetzie/badwords_be/internal/data"
 	"github.com/ggetzie/badwords_be/internal/validator"
 	"github.com/tomasen/realip"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
+// requestID gives each request an ID, returned in the X-Request-ID header
+// and included in everything logged about the request. An ID sent by the
+// client or a proxy is kept if it's reasonable.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		logger := app.logger.With("request_id", id)
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+			logger = logger.With("trace_id", spanContext.TraceID().String())
+		}
+		r = app.contextSetRequestLog(r, &requestLog{logger: logger})
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		isAllowed := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)
+		if !isAllowed {
+			return false
+		}
+	}
+	return true
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
 				w.Header().Set("Connection", "close")
-				app.logger.Error("%s", err, debug.Stack())
+				app.requestLogger(r).Error(fmt.Sprintf("panic: %v", err), "stack", string(debug.Stack()))
 				app.serverErrorResponse(w, r, fmt.Errorf("%s", err))
 			}
 		}()
@@ -63,6 +98,7 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 			clients[ip].lastSeen = time.Now()
 			if !clients[ip].limiter.Allow() {
 				mu.Unlock()
+				app.metrics.rateLimited.Inc()
 				app.rateLimitExceededResponse(w, r)
 				return
 			}
@@ -92,7 +128,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
-		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+		user, err := app.models.Users.GetForToken(r.Context(), data.ScopeAuthentication, token)
 		if err != nil {
 			switch err {
 			case data.ErrRecordNotFound:
@@ -103,6 +139,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 		r = app.contextSetUser(r, user)
+		app.addRequestLogAttrs(r, "user_id", user.ID)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -132,7 +169,7 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
 	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
-		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
@@ -155,6 +192,7 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 			for i := range app.config.cors.trustedOrigins {
 				if origin == app.config.cors.trustedOrigins[i] {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 					if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
 						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
@@ -172,6 +210,7 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 type statusRecorder struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int
 }
 
 func (r *statusRecorder) WriteHeader(statusCode int) {
@@ -179,17 +218,30 @@ func (r *statusRecorder) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		rw := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
 		next.ServeHTTP(rw, r)
 		duration := time.Since(start)
-		app.logger.Info("request completed",
+		app.metrics.observeRequest(r, rw.statusCode, duration.Seconds())
+		app.requestLogger(r).Info("request completed",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"remote_ip", realip.FromRequest(r),
 			"status", rw.statusCode,
+			"bytes", rw.bytes,
 			"duration", duration,
 			"user_agent", r.UserAgent(),
 		)
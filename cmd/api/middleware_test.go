@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	tests := []struct {
+		name     string
+		sent     string
+		wantSame bool
+	}{
+		{"None sent", "", false},
+		{"Valid", "abc-123_DEF.4:5", true},
+		{"Unsafe characters", "abc\"def", false},
+		{"Too long", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/healthcheck", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.sent != "" {
+				req.Header.Set("X-Request-ID", tt.sent)
+			}
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs.Body.Close()
+
+			got := rs.Header.Get("X-Request-ID")
+			if got == "" {
+				t.Fatal("no X-Request-ID header in the response")
+			}
+			if (got == tt.sent) != tt.wantSame {
+				t.Errorf("sent %q and got back %q", tt.sent, got)
+			}
+		})
+	}
+}
+
+func TestLogRequest(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	ts := newTestServer(t, app.routes())
+	user := insertUser(t, app, "alice@example.com")
+	token := login(t, app, user)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/puzzles/999", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Request-ID", "test-request")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(rs.Body)
+	rs.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entry map[string]any
+	err = json.Unmarshal(buf.Bytes(), &entry)
+	if err != nil {
+		t.Fatalf("log output %q isn't a single JSON entry: %v", buf.String(), err)
+	}
+
+	want := map[string]any{
+		"msg":        "request completed",
+		"request_id": "test-request",
+		"user_id":    float64(user.ID),
+		"status":     float64(http.StatusNotFound),
+		"bytes":      float64(len(body)),
+		"path":       "/v1/puzzles/999",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("got %s %v; want %v", key, entry[key], value)
+		}
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	app.requestID(app.recoverPanic(next)).ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusInternalServerError)
+	}
+
+	// the panic is logged, then the server error response
+	var entry map[string]any
+	err := json.NewDecoder(&buf).Decode(&entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry["msg"] != "panic: something went wrong" {
+		t.Errorf("got message %q; want %q", entry["msg"], "panic: something went wrong")
+	}
+	if entry["request_id"] != rr.Header().Get("X-Request-ID") {
+		t.Errorf("got request ID %v; want %q", entry["request_id"], rr.Header().Get("X-Request-ID"))
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "recoverPanic") {
+		t.Error("logged stack doesn't include the panic")
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		format  string
+		level   string
+		wantErr bool
+	}{
+		{"text", "debug", false},
+		{"json", "WARN", false},
+		{"json", "info+2", false},
+		{"xml", "info", true},
+		{"text", "loud", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.level, func(t *testing.T) {
+			_, err := newLogger(io.Discard, tt.format, tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v; want error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
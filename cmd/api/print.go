@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/grid"
+	"github.com/ggetzie/badwords_be/internal/render"
+	"github.com/ggetzie/badwords_be/internal/validator"
+)
+
+func (app *application) printPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	var opts render.PrintOptions
+	opts.Solution = app.readBool(qs, "solution", false, v)
+	opts.LargePrint = app.readBool(qs, "large_print", false, v)
+	opts.TwoUp = app.readBool(qs, "two_up", false, v)
+
+	v.Check(!(opts.LargePrint && opts.TwoUp), "two_up", "cannot be combined with large_print")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	puzzle, permissions, ok := app.readVisiblePuzzle(w, r)
+	if !ok {
+		return
+	}
+	if opts.Solution && !app.canSeeSolution(r, puzzle, permissions) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	p, err := renderPuzzle(puzzle)
+	if err != nil {
+		app.renderErrorResponse(w, r, err)
+		return
+	}
+
+	// render in full before writing so a failure can still get an error
+	// response
+	var buf bytes.Buffer
+	err = render.PDF(&buf, p, opts)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="puzzle-%d.pdf"`, puzzle.ID))
+	w.Write(buf.Bytes())
+}
+
+// renderErrorResponse reports a stored puzzle that couldn't be converted for
+// rendering. Puzzles saved before sizes were limited may be too large to draw.
+func (app *application) renderErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, grid.ErrSize) {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "the puzzle is too large to render")
+		return
+	}
+	app.serverErrorResponse(w, r, err)
+}
+
+// renderPuzzle converts a stored puzzle into the form the renderers draw,
+// numbering squares from the positions of its clues. Oversized puzzles are
+// refused by PuzzleData.Grid before anything is drawn.
+func renderPuzzle(puzzle *data.Puzzle) (render.Puzzle, error) {
+	g, err := puzzle.Content.Grid(puzzle.Width, puzzle.Height)
+	if err != nil {
+		return render.Puzzle{}, err
+	}
+
+	p := render.Puzzle{
+		Title:   puzzle.Title,
+		Author:  puzzle.Author.DisplayName,
+		Grid:    g,
+		Numbers: make(map[grid.Square]int),
+		Circles: puzzle.Content.Circles,
+	}
+	clues := func(entries map[string]data.ClueData) []render.Clue {
+		var list []render.Clue
+		for key, entry := range entries {
+			number, err := strconv.Atoi(key)
+			if err != nil {
+				continue
+			}
+			p.Numbers[grid.Square{Row: entry.Row, Col: entry.Col}] = number
+			list = append(list, render.Clue{Number: number, Text: entry.Clue})
+		}
+		slices.SortFunc(list, func(a, b render.Clue) int { return a.Number - b.Number })
+		return list
+	}
+	p.Across = clues(puzzle.Content.Across)
+	p.Down = clues(puzzle.Content.Down)
+	return p, nil
+}
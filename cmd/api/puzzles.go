@@ -4,14 +4,25 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
+	"github.com/ggetzie/badwords_be/internal/audit"
 	"github.com/ggetzie/badwords_be/internal/data"
 	"github.com/ggetzie/badwords_be/internal/validator"
 )
 
 func (app *application) listPuzzlesHandler(w http.ResponseWriter, r *http.Request) {
+	app.listPuzzles(w, r, 0)
+}
+
+// listPuzzles writes a page of puzzles matching the query string. A non-zero
+// authorID restricts the listing to that author regardless of the query.
+func (app *application) listPuzzles(w http.ResponseWriter, r *http.Request, authorID int) {
 	var input struct {
 		Published string
+		Fields    []string
+		data.PuzzleSearch
 		data.Filters
 	}
 
@@ -19,14 +30,47 @@ func (app *application) listPuzzlesHandler(w http.ResponseWriter, r *http.Reques
 	qs := r.URL.Query()
 
 	input.Published = app.readString(qs, "published", "true")
+	input.Fields = app.readCSV(qs, "fields", data.PuzzleSummaryFields)
+	input.PuzzleSearch.Query = app.readString(qs, "q", "")
+	input.PuzzleSearch.AuthorID = authorID
+	if authorID == 0 {
+		input.PuzzleSearch.AuthorID = app.readInt(qs, "author", 0, v)
+	}
+	input.PuzzleSearch.Width, input.PuzzleSearch.Height = app.readSize(qs, "size", v)
+	input.PuzzleSearch.CreatedAfter = app.readDateTime(qs, "created_after", time.Time{}, v)
+	input.PuzzleSearch.CreatedBefore = app.readDateTime(qs, "created_before", time.Time{}, v)
+	// a puzzle must carry every tag asked for, so repeats would match nothing
+	tags := data.NormalizeTags(app.readCSV(qs, "tag", []string{}))
+	input.PuzzleSearch.Tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+	input.PuzzleSearch.Difficulties = app.readIntList(qs, "difficulty", []int{}, v, 1, 5)
+
+	// search results are ranked by relevance unless another order is requested
+	defaultSort := "-updated_at"
+	if input.PuzzleSearch.Query != "" {
+		defaultSort = "-relevance"
+	}
+
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
-	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-updated_at")
+	input.Filters.PageSize = app.readInt(qs, "page_size", app.config.defaultPageSize, v)
+	input.Filters.Sort = app.readString(qs, "sort", defaultSort)
+	input.Filters.SortSafeList = data.PuzzleSortSafeList
+	input.Filters.After = app.readString(qs, "after", "")
+
+	data.ValidateFilters(v, input.Filters)
+	data.ValidatePuzzleSearch(v, input.PuzzleSearch)
+	data.ValidatePuzzleFields(v, input.Fields)
+	if input.Filters.After != "" {
+		v.Check(data.PuzzleSortSupportsCursor(input.Filters.Sort), "after", "cannot be used when sorting by relevance")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
 	published1, published2 := data.GetPublished(input.Published)
 
 	user := app.contextGetUser(r)
-	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -36,27 +80,64 @@ func (app *application) listPuzzlesHandler(w http.ResponseWriter, r *http.Reques
 		published1 = true
 		published2 = true
 	}
-	puzzles, metadata, err := app.models.Puzzles.List(published1, published2, input.Filters)
+	puzzles, metadata, err := app.models.Puzzles.List(r.Context(), published1, published2, input.PuzzleSearch, input.Fields, input.Filters)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInvalidCursor):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"puzzles": puzzles, "metadata": metadata}, nil)
+	results := make([]map[string]any, 0, len(puzzles))
+	for _, puzzle := range puzzles {
+		app.hideAuthorEmail(puzzle, permissions)
+		results = append(results, puzzle.Select(input.Fields))
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"puzzles": results, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
 
+// hideAuthorEmail clears the author's email address unless the viewer is
+// allowed to manage users.
+func (app *application) hideAuthorEmail(puzzle *data.Puzzle, permissions data.Permissions) {
+	if !permissions.Include(data.Superuser) && !permissions.Include(data.UsersAdmin) {
+		puzzle.Author.Email = ""
+	}
+}
+
 func (app *application) getPuzzleByIdHandler(w http.ResponseWriter, r *http.Request) {
+	puzzle, permissions, ok := app.readVisiblePuzzle(w, r)
+	if !ok {
+		return
+	}
+	app.hideAuthorEmail(puzzle, permissions)
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"puzzle": puzzle}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// readVisiblePuzzle fetches the puzzle named in the URL along with the
+// viewer's permissions, responding with a 404 if it doesn't exist or is an
+// unpublished draft the viewer isn't allowed to see. ok is false once a
+// response has been sent.
+func (app *application) readVisiblePuzzle(w http.ResponseWriter, r *http.Request) (*data.Puzzle, data.Permissions, bool) {
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
-		return
+		return nil, nil, false
 	}
 
-	puzzle, err := app.models.Puzzles.GetByID(id)
+	puzzle, err := app.models.Puzzles.GetByID(r.Context(), id)
 	if err != nil {
 		switch {
 		case err == data.ErrRecordNotFound:
@@ -64,26 +145,40 @@ func (app *application) getPuzzleByIdHandler(w http.ResponseWriter, r *http.Requ
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
-		return
+		return nil, nil, false
 	}
 
 	user := app.contextGetUser(r)
-	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
-		return
+		return nil, nil, false
 	}
 
 	if !puzzle.Published && !permissions.Include(data.PuzzlesUpdate) {
 		app.notFoundResponse(w, r)
-		return
+		return nil, nil, false
 	}
+	return puzzle, permissions, true
+}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"puzzle": puzzle}, nil)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
+// puzzleChanges diffs two versions of a puzzle for the audit log, either of
+// which may be nil. The author is recorded by ID alone so the log doesn't
+// keep a copy of their email and profile.
+func puzzleChanges(before, after *data.Puzzle) map[string]any {
+	var from, to, fromAuthor, toAuthor any
+	if before != nil {
+		from, fromAuthor = before, before.Author.ID
+	}
+	if after != nil {
+		to, toAuthor = after, after.Author.ID
+	}
+	changes := audit.Diff(from, to)
+	delete(changes, "author")
+	if fromAuthor != toAuthor {
+		changes["author_id"] = map[string]any{"from": fromAuthor, "to": toAuthor}
+	}
+	return changes
 }
 
 func (app *application) createPuzzleHandler(w http.ResponseWriter, r *http.Request) {
@@ -94,11 +189,15 @@ func (app *application) createPuzzleHandler(w http.ResponseWriter, r *http.Reque
 		Published   bool            `json:"published"`
 		Width       int             `json:"width"`
 		Height      int             `json:"height"`
+		Tags        []string        `json:"tags"`
+		Theme       string          `json:"theme"`
+		Notes       string          `json:"notes"`
+		Difficulty  int             `json:"difficulty"`
 	}
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.logger.Debug(fmt.Sprintf("Error reading input: %v", r))
+		app.requestLogger(r).Debug("error reading input", "error", err)
 		app.badRequestResponse(w, r, err)
 		return
 	}
@@ -111,20 +210,43 @@ func (app *application) createPuzzleHandler(w http.ResponseWriter, r *http.Reque
 		Width:       input.Width,
 		Height:      input.Height,
 		Author:      *user,
+		Tags:        data.NormalizeTags(input.Tags),
+		Theme:       input.Theme,
+		Notes:       input.Notes,
+		Difficulty:  input.Difficulty,
 	}
 
 	v := validator.New()
 	data.ValidatePuzzle(v, puzzle)
+	data.ValidatePuzzleContent(v, puzzle.Content)
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Puzzles.Insert(puzzle)
+	err = app.models.Puzzles.Insert(r.Context(), puzzle)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if puzzle.Published {
+		app.metrics.publishes.Inc()
+	}
+	app.audit.Record(r, audit.Event{
+		Action:     audit.PuzzleCreated,
+		Actor:      user,
+		TargetType: audit.TargetPuzzle,
+		TargetID:   puzzle.ID,
+		Details:    puzzleChanges(nil, puzzle),
+	})
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	app.hideAuthorEmail(puzzle, permissions)
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/puzzles/%d", puzzle.ID))
@@ -143,7 +265,7 @@ func (app *application) updatePuzzleHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	puzzle, err := app.models.Puzzles.GetByID(id)
+	puzzle, err := app.models.Puzzles.GetByID(r.Context(), id)
 	if err != nil {
 		switch err {
 		case data.ErrRecordNotFound:
@@ -161,6 +283,10 @@ func (app *application) updatePuzzleHandler(w http.ResponseWriter, r *http.Reque
 		Width       *int             `json:"width"`
 		Height      *int             `json:"height"`
 		Published   *bool            `json:"published"`
+		Tags        []string         `json:"tags"`
+		Theme       *string          `json:"theme"`
+		Notes       *string          `json:"notes"`
+		Difficulty  *int             `json:"difficulty"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -169,6 +295,10 @@ func (app *application) updatePuzzleHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// fields are replaced rather than modified in place, so a shallow copy
+	// keeps the old values for the audit log
+	before := *puzzle
+
 	if input.Title != nil {
 		puzzle.Title = *input.Title
 	}
@@ -187,14 +317,29 @@ func (app *application) updatePuzzleHandler(w http.ResponseWriter, r *http.Reque
 	if input.Height != nil {
 		puzzle.Height = *input.Height
 	}
+	if input.Tags != nil {
+		puzzle.Tags = data.NormalizeTags(input.Tags)
+	}
+	if input.Theme != nil {
+		puzzle.Theme = *input.Theme
+	}
+	if input.Notes != nil {
+		puzzle.Notes = *input.Notes
+	}
+	if input.Difficulty != nil {
+		puzzle.Difficulty = *input.Difficulty
+	}
 	v := validator.New()
 	data.ValidatePuzzle(v, puzzle)
+	if input.Content != nil {
+		data.ValidatePuzzleContent(v, puzzle.Content)
+	}
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Puzzles.Update(puzzle)
+	err = app.models.Puzzles.Update(r.Context(), puzzle)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -205,6 +350,29 @@ func (app *application) updatePuzzleHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	action := audit.PuzzleUpdated
+	switch {
+	case puzzle.Published && !before.Published:
+		action = audit.PuzzlePublished
+		app.metrics.publishes.Inc()
+	case !puzzle.Published && before.Published:
+		action = audit.PuzzleUnpublished
+	}
+	app.audit.Record(r, audit.Event{
+		Action:     action,
+		Actor:      app.contextGetUser(r),
+		TargetType: audit.TargetPuzzle,
+		TargetID:   puzzle.ID,
+		Details:    puzzleChanges(&before, puzzle),
+	})
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.hideAuthorEmail(puzzle, permissions)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"puzzle": puzzle}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -219,15 +387,85 @@ func (app *application) deletePuzzleHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Puzzles.Delete(id)
+	puzzle, err := app.models.Puzzles.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Puzzles.Delete(r.Context(), id)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.audit.Record(r, audit.Event{
+		Action:     audit.PuzzleDeleted,
+		Actor:      app.contextGetUser(r),
+		TargetType: audit.TargetPuzzle,
+		TargetID:   puzzle.ID,
+		Details:    puzzleChanges(puzzle, nil),
+	})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "puzzle successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
+
+func (app *application) voteDifficultyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Rating int `json:"rating"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(data.ValidDifficulty(input.Rating), "rating", "must be between 1 and 5"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	puzzle, err := app.models.Puzzles.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !puzzle.Published {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	err = app.models.Puzzles.SetDifficultyVote(r.Context(), puzzle.ID, user.ID, input.Rating)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "difficulty vote recorded"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ggetzie/badwords_be/internal/audit"
+	"github.com/ggetzie/badwords_be/internal/data"
+)
+
+func TestCreatePuzzle(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	token := login(t, app, insertUser(t, app, "alice@example.com", data.PuzzlesCreate))
+
+	valid := map[string]any{
+		"title":       "Cats",
+		"description": "All about cats",
+		"content":     testPuzzleContent(),
+		"width":       3,
+		"height":      3,
+		"tags":        []string{"Animals"},
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		input    map[string]any
+		wantCode int
+	}{
+		{"Valid", token, valid, http.StatusCreated},
+		{"Anonymous", "", valid, http.StatusUnauthorized},
+		{"Missing title", token, map[string]any{"description": "x", "width": 3, "height": 3}, http.StatusUnprocessableEntity},
+		{"Too wide", token, map[string]any{"title": "x", "description": "x", "width": 100000, "height": 3}, http.StatusUnprocessableEntity},
+		{"Bad difficulty", token, map[string]any{"title": "x", "description": "x", "width": 3, "height": 3, "difficulty": 6}, http.StatusUnprocessableEntity},
+		{"Unknown field", token, map[string]any{"title": "x", "colour": "red"}, http.StatusBadRequest},
+		{"Clue number too large", token, withClues(valid, "1", "99999999999"), http.StatusUnprocessableEntity},
+		{"Clue numbers that collide", token, withClues(valid, "1", "01"), http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, response := ts.do(t, http.MethodPost, "/v1/puzzles", tt.token, tt.input)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if code != http.StatusCreated {
+				return
+			}
+			if got := field(t, response, "puzzle", "tags"); fmt.Sprint(got) != "[animals]" {
+				t.Errorf("got tags %v; want [animals]", got)
+			}
+			if got := field(t, response, "puzzle", "published_at"); got != nil {
+				t.Errorf("got published_at %v for a draft; want nil", got)
+			}
+		})
+	}
+}
+
+// withClues returns a copy of a puzzle input whose across clues have the
+// given numbers.
+func withClues(input map[string]any, numbers ...string) map[string]any {
+	content := testPuzzleContent()
+	content.Across = map[string]data.ClueData{}
+	for _, number := range numbers {
+		content.Across[number] = data.ClueData{Clue: "Feline", Answer: "CAT"}
+	}
+	output := maps.Clone(input)
+	output["content"] = content
+	return output
+}
+
+func TestGetPuzzle(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com", data.PuzzlesUpdate)
+	editor := login(t, app, author)
+	solver := login(t, app, insertUser(t, app, "bob@example.com"))
+	member := login(t, app, insertUser(t, app, "carol@example.com", data.StandardPermissions...))
+
+	published := insertPuzzle(t, app, author, true)
+	draft := insertPuzzle(t, app, author, false)
+
+	tests := []struct {
+		name     string
+		token    string
+		id       int
+		wantCode int
+	}{
+		{"Published, anonymous", "", published.ID, http.StatusOK},
+		{"Published, signed in", member, published.ID, http.StatusOK},
+		{"Draft, anonymous", "", draft.ID, http.StatusNotFound},
+		{"Draft, without permission", solver, draft.ID, http.StatusNotFound},
+		{"Draft, with permission", editor, draft.ID, http.StatusOK},
+		{"Missing", "", 999, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, response := ts.do(t, http.MethodGet, fmt.Sprintf("/v1/puzzles/%d", tt.id), tt.token, nil)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if code == http.StatusOK {
+				if got := field(t, response, "puzzle", "author", "email"); got != nil {
+					t.Errorf("response includes the author's email %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestListPuzzles(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com")
+
+	for i := range 5 {
+		insertPuzzle(t, app, author, i%2 == 0)
+	}
+
+	code, response := ts.do(t, http.MethodGet, "/v1/puzzles", "", nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	if got := len(field(t, response, "puzzles").([]any)); got != 3 {
+		t.Errorf("got %d puzzles; want the 3 published", got)
+	}
+
+	// page through them two at a time with the cursor
+	seen := map[float64]bool{}
+	path := "/v1/puzzles?sort=created_at&page_size=2"
+	for path != "" {
+		code, response = ts.do(t, http.MethodGet, path, "", nil)
+		if code != http.StatusOK {
+			t.Fatalf("%s got status %d; want %d", path, code, http.StatusOK)
+		}
+		for _, p := range field(t, response, "puzzles").([]any) {
+			id := p.(map[string]any)["id"].(float64)
+			if seen[id] {
+				t.Fatalf("puzzle %v listed twice", id)
+			}
+			seen[id] = true
+		}
+
+		path = ""
+		if next, _ := field(t, response, "metadata", "next_cursor").(string); next != "" {
+			path = "/v1/puzzles?sort=created_at&page_size=2&after=" + next
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("paging listed %d puzzles; want 3", len(seen))
+	}
+
+	code, _ = ts.do(t, http.MethodGet, "/v1/puzzles?sort=colour", "", nil)
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid sort got status %d; want %d", code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestUpdatePuzzle(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com", data.PuzzlesUpdate)
+	token := login(t, app, author)
+	puzzle := insertPuzzle(t, app, author, false)
+	path := fmt.Sprintf("/v1/puzzles/%d", puzzle.ID)
+
+	code, _ := ts.do(t, http.MethodPatch, path, token, map[string]any{"title": ""})
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("empty title got status %d; want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	code, response := ts.do(t, http.MethodPatch, path, token, map[string]any{"title": "Dogs", "published": true})
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	if got := field(t, response, "puzzle", "title"); got != "Dogs" {
+		t.Errorf("got title %q; want %q", got, "Dogs")
+	}
+	if got := field(t, response, "puzzle", "published_at"); got == nil {
+		t.Error("publishing didn't set published_at")
+	}
+
+	events, _, err := app.models.Audit.List(context.Background(), data.AuditFilter{Action: audit.PuzzlePublished},
+		data.Filters{Page: 1, PageSize: 20, Sort: "-created_at", SortSafeList: data.AuditSortSafeList})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].TargetID != puzzle.ID {
+		t.Errorf("got %d publish events; want 1 for puzzle %d", len(events), puzzle.ID)
+	}
+
+	code, _ = ts.do(t, http.MethodPatch, "/v1/puzzles/999", token, map[string]any{"title": "Dogs"})
+	if code != http.StatusNotFound {
+		t.Errorf("missing puzzle got status %d; want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestPuzzleResponsesHideAuthorEmail(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com", data.PuzzlesCreate)
+	editor := insertUser(t, app, "bob@example.com", data.PuzzlesUpdate)
+	admin := insertUser(t, app, "carol@example.com", data.PuzzlesUpdate, data.UsersAdmin)
+
+	input := map[string]any{"title": "Cats", "description": "All about cats", "content": testPuzzleContent(), "width": 3, "height": 3}
+	code, response := ts.do(t, http.MethodPost, "/v1/puzzles", login(t, app, author), input)
+	if code != http.StatusCreated {
+		t.Fatalf("creating got status %d; want %d", code, http.StatusCreated)
+	}
+	if got := field(t, response, "puzzle", "author", "email"); got != nil {
+		t.Errorf("creating got author email %v; want it hidden", got)
+	}
+	path := fmt.Sprintf("/v1/puzzles/%v", field(t, response, "puzzle", "id"))
+
+	tests := []struct {
+		name  string
+		token string
+		want  any
+	}{
+		{"Editor", login(t, app, editor), nil},
+		{"User admin", login(t, app, admin), "alice@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, response := ts.do(t, http.MethodPatch, path, tt.token, map[string]any{"title": tt.name})
+			if code != http.StatusOK {
+				t.Fatalf("got status %d; want %d", code, http.StatusOK)
+			}
+			if got := field(t, response, "puzzle", "author", "email"); got != tt.want {
+				t.Errorf("got author email %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdatePuzzleOldClueNumbers(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com", data.PuzzlesUpdate)
+	token := login(t, app, author)
+
+	// saved before clue numbers were checked
+	puzzle := insertPuzzle(t, app, author, false)
+	puzzle.Content.Across["1a"] = data.ClueData{Clue: "Feline", Answer: "CAT"}
+	puzzle.Content.Across["01"] = data.ClueData{Clue: "Feline", Answer: "CAT"}
+	puzzle.Version = 1
+	err := app.models.Puzzles.Update(context.Background(), puzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/v1/puzzles/%d", puzzle.ID)
+
+	code, _ := ts.do(t, http.MethodPatch, path, token, map[string]any{"title": "Dogs"})
+	if code != http.StatusOK {
+		t.Errorf("changing the title got status %d; want %d", code, http.StatusOK)
+	}
+
+	code, _ = ts.do(t, http.MethodPatch, path, token, map[string]any{"content": puzzle.Content})
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("resending the content got status %d; want %d", code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestDeletePuzzle(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com", data.PuzzlesDelete)
+	token := login(t, app, author)
+	puzzle := insertPuzzle(t, app, author, true)
+	path := fmt.Sprintf("/v1/puzzles/%d", puzzle.ID)
+
+	code, _ := ts.do(t, http.MethodDelete, path, token, nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	code, _ = ts.do(t, http.MethodGet, path, "", nil)
+	if code != http.StatusNotFound {
+		t.Errorf("getting the deleted puzzle got status %d; want %d", code, http.StatusNotFound)
+	}
+	code, _ = ts.do(t, http.MethodDelete, path, token, nil)
+	if code != http.StatusNotFound {
+		t.Errorf("deleting it again got status %d; want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestVoteDifficulty(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com")
+	token := login(t, app, insertUser(t, app, "bob@example.com"))
+	published := insertPuzzle(t, app, author, true)
+	draft := insertPuzzle(t, app, author, false)
+
+	tests := []struct {
+		name     string
+		token    string
+		id       int
+		rating   int
+		wantCode int
+	}{
+		{"Valid", token, published.ID, 4, http.StatusOK},
+		{"Changed vote", token, published.ID, 2, http.StatusOK},
+		{"Anonymous", "", published.ID, 4, http.StatusUnauthorized},
+		{"Out of range", token, published.ID, 6, http.StatusUnprocessableEntity},
+		{"Draft", token, draft.ID, 4, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := ts.do(t, http.MethodPut, fmt.Sprintf("/v1/puzzles/%d/difficulty", tt.id), tt.token,
+				map[string]int{"rating": tt.rating})
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	_, response := ts.do(t, http.MethodGet, fmt.Sprintf("/v1/puzzles/%d", published.ID), "", nil)
+	if got := field(t, response, "puzzle", "solver_votes"); got != 1.0 {
+		t.Errorf("got %v votes; want 1", got)
+	}
+	if got := field(t, response, "puzzle", "solver_difficulty"); got != 2.0 {
+		t.Errorf("got solver difficulty %v; want 2", got)
+	}
+}
+
+func TestGridSVG(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	puzzle := insertPuzzle(t, app, insertUser(t, app, "alice@example.com"), true)
+
+	rs, err := ts.Client().Get(fmt.Sprintf("%s/v1/puzzles/%d/grid.svg", ts.URL, puzzle.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d; want %d", rs.StatusCode, http.StatusOK)
+	}
+	if got := rs.Header.Get("Content-Type"); !strings.HasPrefix(got, "image/svg+xml") {
+		t.Errorf("got content type %q; want image/svg+xml", got)
+	}
+}
+
+func TestPrintSolution(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com")
+	reader := insertUser(t, app, "bob@example.com")
+	editor := insertUser(t, app, "carol@example.com", data.PuzzlesUpdate)
+	puzzle := insertPuzzle(t, app, author, true)
+
+	tests := []struct {
+		name     string
+		token    string
+		query    string
+		wantCode int
+	}{
+		{"Anonymous puzzle", "", "", http.StatusOK},
+		{"Anonymous solution", "", "?solution=true", http.StatusForbidden},
+		{"Reader solution", login(t, app, reader), "?solution=true", http.StatusForbidden},
+		{"Author solution", login(t, app, author), "?solution=true", http.StatusOK},
+		{"Editor solution", login(t, app, editor), "?solution=true", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := ts.do(t, http.MethodGet, fmt.Sprintf("/v1/puzzles/%d/print.pdf%s", puzzle.ID, tt.query), tt.token, nil)
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRenderOversizedPuzzle(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	// stored before sizes were limited
+	puzzle := insertPuzzle(t, app, insertUser(t, app, "alice@example.com"), true)
+	puzzle.Width = 100000
+	puzzle.Version = 1
+	err := app.models.Puzzles.Update(context.Background(), puzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"grid.png", "grid.svg", "print.pdf", "share.png?time=60"} {
+		t.Run(path, func(t *testing.T) {
+			code, _ := ts.do(t, http.MethodGet, fmt.Sprintf("/v1/puzzles/%d/%s", puzzle.ID, path), "", nil)
+			if code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d; want %d", code, http.StatusUnprocessableEntity)
+			}
+		})
+	}
+}
+
+func TestPublicListings(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com", data.StandardPermissions...)
+
+	puzzle := insertPuzzle(t, app, author, true)
+	puzzle.Tags = []string{"animals"}
+	puzzle.Version = 1
+	err := app.models.Puzzles.Update(context.Background(), puzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := &data.Collection{Title: "Favourites", OwnerID: author.ID, Published: true}
+	err = app.models.Collections.Insert(context.Background(), collection, []int{puzzle.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		keys []string
+		want any
+	}{
+		{"/v1/tags", []string{"tags"}, []any{map[string]any{"name": "animals", "puzzles": float64(1)}}},
+		{fmt.Sprintf("/v1/authors/%d", author.ID), []string{"author", "published_puzzles"}, float64(1)},
+		{"/v1/puzzles?tag=animals&tag=Animals", []string{"metadata", "total_records"}, float64(1)},
+		{"/v1/clues?answer=cat", []string{"metadata", "total_records"}, float64(1)},
+		{"/v1/collections", []string{"metadata", "total_records"}, float64(1)},
+		{fmt.Sprintf("/v1/collections/%d", collection.ID), []string{"collection", "puzzle_count"}, float64(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			code, response := ts.do(t, http.MethodGet, tt.path, "", nil)
+			if code != http.StatusOK {
+				t.Fatalf("got status %d; want %d", code, http.StatusOK)
+			}
+			if got := field(t, response, tt.keys...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %s %v; want %v", strings.Join(tt.keys, "."), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateCollection(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com", data.CollectionsCreate)
+	token := login(t, app, author)
+	first := insertPuzzle(t, app, author, true)
+	second := insertPuzzle(t, app, author, true)
+
+	input := map[string]any{"title": "Favourites", "published": true, "puzzle_ids": []int{second.ID, first.ID}}
+	code, resp := ts.do(t, http.MethodPost, "/v1/collections", token, input)
+	if code != http.StatusCreated {
+		t.Fatalf("got status %d; want %d", code, http.StatusCreated)
+	}
+	if got := field(t, resp, "collection", "puzzle_count"); got != float64(2) {
+		t.Errorf("got puzzle_count %v; want 2", got)
+	}
+
+	id := int(field(t, resp, "collection", "id").(float64))
+	puzzles, err := app.models.Collections.GetPuzzles(context.Background(), id, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, p := range puzzles {
+		ids = append(ids, p.ID)
+	}
+	if want := []int{second.ID, first.ID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got puzzles %v; want %v", ids, want)
+	}
+}
+
+func TestSetCollectionPuzzles(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	author := insertUser(t, app, "alice@example.com")
+	editor := insertUser(t, app, "bob@example.com", data.CollectionsUpdate)
+	token := login(t, app, editor)
+	puzzle := insertPuzzle(t, app, author, true)
+	collection := &data.Collection{Title: "Favourites", OwnerID: editor.ID, Published: true}
+	err := app.models.Collections.Insert(context.Background(), collection, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/v1/collections/%d/puzzles", collection.ID)
+	code, resp := ts.do(t, http.MethodPut, path, token, map[string]any{"puzzle_ids": []int{puzzle.ID}})
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	puzzles := field(t, resp, "puzzles").([]any)
+	if len(puzzles) != 1 {
+		t.Fatalf("got %d puzzles; want 1", len(puzzles))
+	}
+	if email, ok := field(t, puzzles[0].(map[string]any), "author", "email").(string); ok {
+		t.Errorf("got author email %q; want it hidden", email)
+	}
+}
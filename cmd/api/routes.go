@@ -9,8 +9,14 @@ import (
 
 func (app *application) routes() http.Handler {
 	router := httprouter.New()
+	app.metrics.router = router
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/healthcheck/live", app.livenessHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/healthcheck/ready", app.readinessHandler)
+	if app.config.metrics.port == 0 {
+		router.Handler(http.MethodGet, "/metrics", app.metrics.handler())
+	}
 
 	// Puzzle Routes
 	router.HandlerFunc(http.MethodGet, "/v1/puzzles", app.listPuzzlesHandler)
@@ -18,17 +24,69 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/v1/puzzles/:id", app.getPuzzleByIdHandler)
 	router.HandlerFunc(http.MethodPatch, "/v1/puzzles/:id", app.requirePermission(data.PuzzlesUpdate, app.updatePuzzleHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/puzzles/:id", app.requirePermission(data.PuzzlesDelete, app.deletePuzzleHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/puzzles/:id/analysis", app.requirePermission(data.PuzzlesUpdate, app.getPuzzleAnalysisHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/puzzles/:id/print.pdf", app.printPuzzleHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/puzzles/:id/grid.svg", app.gridSVGHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/puzzles/:id/grid.png", app.gridPNGHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/puzzles/:id/share.png", app.shareCardHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/puzzles/:id/difficulty", app.requireActivatedUser(app.voteDifficultyHandler))
+
+	// Import Routes
+	router.HandlerFunc(http.MethodPost, "/v1/imports", app.requirePermission(data.PuzzlesCreate, app.createImportHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/imports/:id", app.requireActivatedUser(app.getImportHandler))
+
+	// Export Routes
+	router.HandlerFunc(http.MethodGet, "/v1/exports/puzzles", app.requirePermission(data.Superuser, app.exportPuzzlesHandler))
+
+	// Collection Routes
+	router.HandlerFunc(http.MethodGet, "/v1/collections", app.listCollectionsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/collections", app.requirePermission(data.CollectionsCreate, app.createCollectionHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/collections/:id", app.getCollectionHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/collections/:id", app.requirePermission(data.CollectionsUpdate, app.updateCollectionHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/collections/:id", app.requirePermission(data.CollectionsDelete, app.deleteCollectionHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/collections/:id/puzzles", app.requirePermission(data.CollectionsUpdate, app.setCollectionPuzzlesHandler))
+
+	// Word List Routes
+	router.HandlerFunc(http.MethodGet, "/v1/wordlists", app.requireActivatedUser(app.listWordListsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/wordlists", app.requireActivatedUser(app.createWordListHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/wordlists/:id", app.requireActivatedUser(app.deleteWordListHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/wordlists/:id/words", app.requireActivatedUser(app.uploadWordsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/wordlists/:id/words/:word", app.requireActivatedUser(app.setWordHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/wordlists/:id/words/:word", app.requireActivatedUser(app.deleteWordHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/words", app.requireActivatedUser(app.lookupWordsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/fill", app.requireActivatedUser(app.fillGridHandler))
+
+	// Tag Routes
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.listTagsHandler)
+
+	// Clue Routes
+	router.HandlerFunc(http.MethodGet, "/v1/clues", app.listCluesHandler)
+
+	// Author Routes
+	router.HandlerFunc(http.MethodGet, "/v1/authors/:id", app.getAuthorHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/authors/:id/puzzles", app.listAuthorPuzzlesHandler)
 
 	// User Routes
 	router.HandlerFunc(http.MethodGet, "/v1/user", app.requirePermission(data.UsersRead, app.getCurrentUserHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.requirePermission(data.UsersCreate, app.addUserHandler))
 	router.HandlerFunc(http.MethodPut, "/v1/user/password", app.requireAuthenticatedUser(app.changePasswordHandler))
 	router.HandlerFunc(http.MethodPut, "/v1/user", app.requireActivatedUser(app.updateUserHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/user", app.requireAuthenticatedUser(app.deleteUserHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/user/export", app.requireAuthenticatedUser(app.exportUserDataHandler))
+
+	// Admin Routes
+	router.HandlerFunc(http.MethodGet, "/v1/admin/users", app.requirePermission(data.UsersAdmin, app.listUsersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/admin/users/:id", app.requirePermission(data.UsersAdmin, app.getUserHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/admin/users/:id", app.requirePermission(data.UsersAdmin, app.updateUserByIdHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/users/:id", app.requirePermission(data.UsersAdmin, app.deleteUserByIdHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/users/:id/password", app.requirePermission(data.UsersAdmin, app.resetUserPasswordHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/users/:id/sessions", app.requirePermission(data.UsersAdmin, app.revokeUserSessionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/admin/audit", app.requirePermission(data.Superuser, app.listAuditEventsHandler))
 
 	// Authentication routes
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/logout", app.logoutHandler)
 
-	return app.recoverPanic(app.enableCORS(app.rateLimit(app.logRequest(app.authenticate(router)))))
+	return app.trace(app.requestID(app.recoverPanic(app.enableCORS(app.rateLimit(app.logRequest(app.authenticate(router)))))))
 
 }
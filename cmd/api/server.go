@@ -5,21 +5,45 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 )
 
 func (app *application) serve() error {
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.config.port),
 		Handler:      app.routes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  app.config.server.idleTimeout,
+		ReadTimeout:  app.config.server.readTimeout,
+		WriteTimeout: app.config.server.writeTimeout,
 		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		BaseContext: func(net.Listener) context.Context {
+			return app.ctx
+		},
+	}
+
+	// metrics on their own port can be kept off the public network
+	var metricsSrv *http.Server
+	if app.config.metrics.port != 0 {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", app.metrics.handler())
+		metricsSrv = &http.Server{
+			Addr:         fmt.Sprintf(":%d", app.config.metrics.port),
+			Handler:      mux,
+			ReadTimeout:  app.config.server.readTimeout,
+			WriteTimeout: app.config.server.writeTimeout,
+			ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		}
+		go func() {
+			app.logger.Info("starting metrics server", "addr", metricsSrv.Addr)
+			err := metricsSrv.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.Error(err.Error(), "addr", metricsSrv.Addr)
+			}
+		}()
 	}
 
 	shutdownError := make(chan error)
@@ -31,14 +55,36 @@ func (app *application) serve() error {
 
 		app.logger.Info("shutting down server", "signal", s.String())
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.shutdownTimeout)
 		defer cancel()
+		if metricsSrv != nil {
+			err := metricsSrv.Shutdown(ctx)
+			if err != nil {
+				app.logger.Error(err.Error(), "addr", metricsSrv.Addr)
+			}
+		}
 		err := srv.Shutdown(ctx)
 		if err != nil {
+			// cancel the requests still running so they stop querying the
+			// database
+			app.cancel()
 			shutdownError <- err
+			return
 		}
+
 		app.logger.Info("completing background tasks", "addr", srv.Addr)
-		app.wg.Wait()
+		done := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			app.logger.Warn("cancelling background tasks", "addr", srv.Addr)
+			app.cancel()
+			<-done
+		}
 		shutdownError <- nil
 	}()
 
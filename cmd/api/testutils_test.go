@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/audit"
+	"github.com/ggetzie/badwords_be/internal/data"
+)
+
+const testPassword = "pa55word1234"
+
+// newTestApplication returns an application backed by the in-memory store
+// that logs nothing.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	cfg, _, err := loadConfig([]string{"-env", "testing", "-in-memory"}, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	models := data.NewMemoryModels()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return &application{
+		config:  cfg,
+		logger:  logger,
+		models:  models,
+		audit:   audit.New(models.Audit, logger),
+		metrics: newMetrics(nil),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+type testServer struct {
+	*httptest.Server
+}
+
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	return &testServer{ts}
+}
+
+// do sends a request with an optional JSON body and bearer token and returns
+// the status code and the decoded JSON response.
+func (ts *testServer) do(t *testing.T, method, urlPath, token string, body any) (int, map[string]any) {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqBody = bytes.NewReader(js)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+urlPath, reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	raw, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response map[string]any
+	if len(raw) > 0 && rs.Header.Get("Content-Type") == "application/json" {
+		err = json.Unmarshal(raw, &response)
+		if err != nil {
+			t.Fatalf("decoding %s %s response: %v", method, urlPath, err)
+		}
+	}
+	return rs.StatusCode, response
+}
+
+// insertUser adds an activated user with the given permissions directly to
+// the store.
+func insertUser(t *testing.T, app *application, email string, permissions ...string) *data.User {
+	t.Helper()
+
+	user := &data.User{
+		Email:       email,
+		FullName:    "Test User",
+		DisplayName: email,
+		Activated:   true,
+	}
+	err := user.Password.Set(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	err = app.models.Users.Insert(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(permissions) > 0 {
+		err = app.models.Permissions.AddForUser(ctx, user.ID, permissions...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return user
+}
+
+// login returns an authentication token for the user, skipping the password
+// check so tests don't pay for bcrypt on every request.
+func login(t *testing.T, app *application, user *data.User) string {
+	t.Helper()
+
+	token, err := app.models.Tokens.New(context.Background(), user.ID, time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token.Plaintext
+}
+
+// field follows a path of keys through a decoded JSON response.
+func field(t *testing.T, response map[string]any, keys ...string) any {
+	t.Helper()
+
+	var value any = response
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			t.Fatalf("%v: %q is not inside an object", keys, key)
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// insertPuzzle adds a small puzzle by the user directly to the store.
+func insertPuzzle(t *testing.T, app *application, author *data.User, published bool) *data.Puzzle {
+	t.Helper()
+
+	puzzle := &data.Puzzle{
+		Title:       "Test Puzzle",
+		Description: "A puzzle for testing",
+		Content:     testPuzzleContent(),
+		Width:       3,
+		Height:      3,
+		Author:      *author,
+		Published:   published,
+		Tags:        []string{},
+	}
+	err := app.models.Puzzles.Insert(context.Background(), puzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return puzzle
+}
+
+func testPuzzleContent() data.PuzzleData {
+	return data.PuzzleData{
+		Across: map[string]data.ClueData{
+			"1": {Row: 0, Col: 0, Clue: "Feline", Answer: "CAT"},
+		},
+		Down: map[string]data.ClueData{
+			"1": {Row: 0, Col: 0, Clue: "Taxi", Answer: "CAB"},
+		},
+	}
+}
@@ -3,8 +3,8 @@ package main
 import (
 	"errors"
 	"net/http"
-	"time"
 
+	"github.com/ggetzie/badwords_be/internal/audit"
 	"github.com/ggetzie/badwords_be/internal/data"
 	"github.com/ggetzie/badwords_be/internal/validator"
 )
@@ -32,10 +32,15 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			app.metrics.logins.WithLabelValues("failure").Inc()
+			app.audit.Record(r, audit.Event{
+				Action:  audit.LoginFailed,
+				Details: map[string]any{"email": input.Email, "reason": "unknown email"},
+			})
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -49,16 +54,31 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	if !match {
+		app.metrics.logins.WithLabelValues("failure").Inc()
+		app.audit.Record(r, audit.Event{
+			Action:     audit.LoginFailed,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			Details:    map[string]any{"email": input.Email, "reason": "wrong password"},
+		})
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	token, err := app.models.Tokens.New(user.ID, 30*24*time.Hour, data.ScopeAuthentication)
+	token, err := app.models.Tokens.New(r.Context(), user.ID, app.config.authTokenTTL, data.ScopeAuthentication)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.metrics.logins.WithLabelValues("success").Inc()
+	app.audit.Record(r, audit.Event{
+		Action:     audit.Login,
+		Actor:      user,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+	})
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token, "user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -71,7 +91,7 @@ func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
 		app.writeJSON(w, http.StatusOK, envelope{"message": "already logged out"}, nil)
 		return
 	}
-	err := app.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	err := app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeAuthentication, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+)
+
+func TestCreateAuthenticationToken(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	insertUser(t, app, "alice@example.com", data.UsersRead)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantCode int
+	}{
+		{"Valid credentials", "alice@example.com", testPassword, http.StatusCreated},
+		{"Wrong password", "alice@example.com", "wrongpassword", http.StatusUnauthorized},
+		{"Unknown email", "bob@example.com", testPassword, http.StatusUnauthorized},
+		{"Invalid email", "alice", testPassword, http.StatusUnprocessableEntity},
+		{"Short password", "alice@example.com", "short", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, response := ts.do(t, http.MethodPost, "/v1/tokens/authentication", "",
+				map[string]string{"email": tt.email, "password": tt.password})
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+			if code != http.StatusCreated {
+				return
+			}
+
+			token, _ := field(t, response, "authentication_token", "token").(string)
+			code, _ = ts.do(t, http.MethodGet, "/v1/user", token, nil)
+			if code != http.StatusOK {
+				t.Errorf("using the new token got status %d; want %d", code, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	token := login(t, app, insertUser(t, app, "alice@example.com", data.UsersRead))
+
+	code, _ := ts.do(t, http.MethodPost, "/v1/logout", token, nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	code, _ = ts.do(t, http.MethodGet, "/v1/user", token, nil)
+	if code != http.StatusUnauthorized {
+		t.Errorf("after logout got status %d; want %d", code, http.StatusUnauthorized)
+	}
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/ggetzie/badwords_be/internal/audit"
 	"github.com/ggetzie/badwords_be/internal/data"
 	"github.com/ggetzie/badwords_be/internal/validator"
 )
@@ -41,7 +44,7 @@ func (app *application) addUserHandler(w http.ResponseWriter, r *http.Request) {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	err = app.models.Users.Insert(user)
+	err = app.models.Users.Insert(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -56,12 +59,27 @@ func (app *application) addUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.ID, data.StandardPermissions...)
+	err = app.models.Permissions.AddForUser(r.Context(), user.ID, data.StandardPermissions...)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	actor := app.contextGetUser(r)
+	app.audit.Record(r, audit.Event{
+		Action:     audit.UserCreated,
+		Actor:      actor,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+	})
+	app.audit.Record(r, audit.Event{
+		Action:     audit.PermissionsGranted,
+		Actor:      actor,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]any{"permissions": data.StandardPermissions},
+	})
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -72,7 +90,7 @@ func (app *application) addUserHandler(w http.ResponseWriter, r *http.Request) {
 func (app *application) getCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
 	// get a user by id
 	user := app.contextGetUser(r)
-	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -107,7 +125,7 @@ func (app *application) changePasswordHandler(w http.ResponseWriter, r *http.Req
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.models.Users.Update(user)
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -122,9 +140,11 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 	// update the current user's profile
 	user := app.contextGetUser(r)
 	var input struct {
-		FullName    string `json:"full_name"`
-		DisplayName string `json:"display_name"`
-		Email       string `json:"email"`
+		FullName    string  `json:"full_name"`
+		DisplayName string  `json:"display_name"`
+		Email       string  `json:"email"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -141,11 +161,17 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 	user.Email = input.Email
 	user.FullName = input.FullName
 	user.DisplayName = input.DisplayName
+	if input.Bio != nil {
+		user.Bio = *input.Bio
+	}
+	if input.AvatarURL != nil {
+		user.AvatarURL = *input.AvatarURL
+	}
 	if data.ValidateUser(v, user); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	err = app.models.Users.Update(user)
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -164,3 +190,159 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// exportUserDataHandler sends everything stored about the current user as a
+// single JSON document. Tokens are listed by scope and expiry only.
+func (app *application) exportUserDataHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	puzzles := []*data.Puzzle{}
+	err = app.models.Puzzles.Export(r.Context(), data.PuzzleExportFilter{AuthorID: user.ID}, func(puzzle *data.Puzzle) error {
+		puzzles = append(puzzles, puzzle)
+		return nil
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	votes, err := app.models.Puzzles.GetDifficultyVotesForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	lists, err := app.models.WordLists.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	wordLists := []envelope{}
+	for _, list := range lists {
+		if list.OwnerID != user.ID {
+			continue
+		}
+		words, err := app.models.WordLists.GetEntries(r.Context(), list.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		wordLists = append(wordLists, envelope{"word_list": list, "words": words})
+	}
+
+	ownCollections, err := app.models.Collections.GetAllForOwner(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	collections := []envelope{}
+	for _, collection := range ownCollections {
+		members, err := app.models.Collections.GetPuzzles(r.Context(), collection.ID, false)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		puzzleIDs := make([]int, len(members))
+		for i, puzzle := range members {
+			puzzleIDs[i] = puzzle.ID
+		}
+		collections = append(collections, envelope{"collection": collection, "puzzle_ids": puzzleIDs})
+	}
+
+	imports, err := app.models.Imports.GetAllForOwner(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sessions, err := app.models.Tokens.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="badwords-user-%d.json"`, user.ID))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"exported_at":      time.Now().UTC(),
+		"user":             user,
+		"permissions":      permissions,
+		"puzzles":          puzzles,
+		"difficulty_votes": votes,
+		"word_lists":       wordLists,
+		"collections":      collections,
+		"imports":          imports,
+		"sessions":         sessions,
+	}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteUserHandler deletes the current user's account once they've confirmed
+// their password. Published puzzles are kept under a placeholder author
+// unless delete_puzzles is set.
+func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password      string `json:"password"`
+		DeletePuzzles bool   `json:"delete_puzzles"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Password != "", "password", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !match {
+		v.AddError("password", "is incorrect")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Delete(r.Context(), user.ID, input.DeletePuzzles)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// the account and the personal data attached to it are gone, so the event
+	// doesn't record who asked or from where
+	app.audit.Record(r, audit.Event{
+		Action:     audit.UserDeleted,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]any{"delete_puzzles": input.DeletePuzzles},
+		Anonymous:  true,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your account has been deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
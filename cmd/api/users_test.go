@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ggetzie/badwords_be/internal/audit"
+	"github.com/ggetzie/badwords_be/internal/data"
+)
+
+func TestAddUser(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	admin := login(t, app, insertUser(t, app, "admin@example.com", data.UsersCreate))
+	other := login(t, app, insertUser(t, app, "other@example.com"))
+
+	input := map[string]string{
+		"email":        "carol@example.com",
+		"full_name":    "Carol",
+		"display_name": "carol",
+		"password":     testPassword,
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{"Anonymous", "", http.StatusUnauthorized},
+		{"Without permission", other, http.StatusForbidden},
+		{"Valid", admin, http.StatusCreated},
+		{"Duplicate email", admin, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := ts.do(t, http.MethodPost, "/v1/users", tt.token, input)
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	user, err := app.models.Users.GetByEmail(context.Background(), "carol@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	permissions, err := app.models.Permissions.GetAllForUser(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !permissions.Include(data.PuzzlesCreate) {
+		t.Errorf("new user's permissions %v don't include %q", permissions, data.PuzzlesCreate)
+	}
+}
+
+func TestUpdateCurrentUser(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	token := login(t, app, insertUser(t, app, "alice@example.com", data.UsersRead))
+	insertUser(t, app, "bob@example.com")
+
+	tests := []struct {
+		name     string
+		email    string
+		wantCode int
+	}{
+		{"Valid", "alice@example.org", http.StatusOK},
+		{"Invalid email", "alice", http.StatusUnprocessableEntity},
+		{"Duplicate email", "bob@example.com", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := ts.do(t, http.MethodPut, "/v1/user", token, map[string]string{
+				"email":        tt.email,
+				"full_name":    "Alice",
+				"display_name": "alice",
+			})
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	code, response := ts.do(t, http.MethodGet, "/v1/user", token, nil)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+	if got := field(t, response, "user", "email"); got != "alice@example.org" {
+		t.Errorf("got email %q; want %q", got, "alice@example.org")
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	user := insertUser(t, app, "alice@example.com")
+	token := login(t, app, user)
+
+	code, _ := ts.do(t, http.MethodPut, "/v1/user/password", token, map[string]string{"password": "short"})
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("short password got status %d; want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	code, _ = ts.do(t, http.MethodPut, "/v1/user/password", token, map[string]string{"password": "anotherpa55word"})
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	code, _ = ts.do(t, http.MethodPost, "/v1/tokens/authentication", "",
+		map[string]string{"email": user.Email, "password": "anotherpa55word"})
+	if code != http.StatusCreated {
+		t.Errorf("logging in with the new password got status %d; want %d", code, http.StatusCreated)
+	}
+}
+
+func TestDeleteCurrentUser(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	user := insertUser(t, app, "alice@example.com")
+	token := login(t, app, user)
+
+	published := insertPuzzle(t, app, user, true)
+	draft := insertPuzzle(t, app, user, false)
+
+	code, _ := ts.do(t, http.MethodDelete, "/v1/user", token, map[string]string{"password": "wrongpassword"})
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("wrong password got status %d; want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	code, _ = ts.do(t, http.MethodDelete, "/v1/user", token, map[string]string{"password": testPassword})
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	ctx := context.Background()
+	_, err := app.models.Users.GetByID(ctx, user.ID)
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		t.Errorf("getting the deleted user got %v; want %v", err, data.ErrRecordNotFound)
+	}
+
+	// published puzzles are kept under the placeholder author
+	puzzle, err := app.models.Puzzles.GetByID(ctx, published.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if puzzle.Author.Email != data.DeletedUserEmail {
+		t.Errorf("got author %q; want %q", puzzle.Author.Email, data.DeletedUserEmail)
+	}
+	_, err = app.models.Puzzles.GetByID(ctx, draft.ID)
+	if !errors.Is(err, data.ErrRecordNotFound) {
+		t.Errorf("getting the deleted user's draft got %v; want %v", err, data.ErrRecordNotFound)
+	}
+
+	code, _ = ts.do(t, http.MethodGet, "/v1/user", token, nil)
+	if code != http.StatusUnauthorized {
+		t.Errorf("using the deleted user's token got status %d; want %d", code, http.StatusUnauthorized)
+	}
+}
+
+func TestDeleteCurrentUserScrubsAuditLog(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	user := insertUser(t, app, "alice@example.com", data.PuzzlesCreate)
+	token := login(t, app, user)
+
+	// events the user acted in, was the target of, or was named in
+	for _, email := range []string{"alice@example.com", "Alice@Example.com"} {
+		ts.do(t, http.MethodPost, "/v1/tokens/authentication", "",
+			map[string]string{"email": email, "password": "wrongpassword"})
+	}
+	code, _ := ts.do(t, http.MethodPost, "/v1/puzzles", token, map[string]any{
+		"title": "Cats", "description": "All about cats", "width": 3, "height": 3, "content": testPuzzleContent(),
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("creating a puzzle got status %d; want %d", code, http.StatusCreated)
+	}
+
+	code, _ = ts.do(t, http.MethodDelete, "/v1/user", token, map[string]string{"password": testPassword})
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	events, _, err := app.models.Audit.List(context.Background(), data.AuditFilter{},
+		data.Filters{Page: 1, PageSize: 100, Sort: "created_at", SortSafeList: data.AuditSortSafeList})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) < 4 {
+		t.Fatalf("got %d events; want at least 4", len(events))
+	}
+	for _, event := range events {
+		js, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(strings.ToLower(string(js)), "alice@example.com") {
+			t.Errorf("%s event still names the user: %s", event.Action, js)
+		}
+		// failed logins may have come from someone else, so keep their IP
+		if event.Action != audit.LoginFailed && event.IP != "" {
+			t.Errorf("%s event still records the user's IP", event.Action)
+		}
+	}
+}
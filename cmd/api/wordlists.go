@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/validator"
+	"github.com/ggetzie/badwords_be/internal/wordlist"
+)
+
+const (
+	maxWordListUploadBytes = 10 << 20
+	maxWordListUploadWords = 250_000
+)
+
+func (app *application) listWordListsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	lists, err := app.models.WordLists.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"word_lists": lists}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createWordListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	list := &data.WordList{
+		Name:    strings.TrimSpace(input.Name),
+		OwnerID: user.ID,
+	}
+
+	v := validator.New()
+	if data.ValidateWordList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.WordLists.Insert(r.Context(), list)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/wordlists/%d", list.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"word_list": list}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getEditableWordList loads the word list named in the URL and checks the
+// current user may change it: their own lists, or the house list for users
+// with the wordlists:house permission. It writes an error response and
+// returns nil otherwise.
+func (app *application) getEditableWordList(w http.ResponseWriter, r *http.Request) *data.WordList {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	list, err := app.models.WordLists.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	user := app.contextGetUser(r)
+	if !list.CanRead(user) {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+	if list.Shared {
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return nil
+		}
+		if !permissions.Include(data.Superuser) && !permissions.Include(data.WordListsHouse) {
+			app.notPermittedResponse(w, r)
+			return nil
+		}
+	}
+	return list
+}
+
+func (app *application) deleteWordListHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getEditableWordList(w, r)
+	if list == nil {
+		return
+	}
+	if list.Shared {
+		app.errorResponse(w, r, http.StatusConflict, "the house word list cannot be deleted")
+		return
+	}
+
+	err := app.models.WordLists.Delete(r.Context(), list.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "word list successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) uploadWordsHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getEditableWordList(w, r)
+	if list == nil {
+		return
+	}
+
+	replace := app.readString(r.URL.Query(), "replace", "false") == "true"
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxWordListUploadBytes)
+	entries, lineErrors, err := wordlist.Parse(r.Body, maxWordListUploadWords)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxWordListUploadBytes))
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+	if len(entries) == 0 && len(lineErrors) == 0 {
+		app.badRequestResponse(w, r, errors.New("body must contain at least one word"))
+		return
+	}
+
+	if len(entries) > 0 {
+		err = app.models.WordLists.AddWords(r.Context(), list.ID, entries, replace)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	list, err = app.models.WordLists.GetByID(r.Context(), list.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"word_list": list, "added": len(entries), "errors": lineErrors}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) setWordHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getEditableWordList(w, r)
+	if list == nil {
+		return
+	}
+
+	word, err := app.readStringParam(r, "word")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Score *int   `json:"score"`
+		Notes string `json:"notes"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry := wordlist.Entry{
+		Word:  wordlist.Normalize(word),
+		Score: wordlist.DefaultScore,
+		Notes: strings.TrimSpace(input.Notes),
+	}
+	if input.Score != nil {
+		entry.Score = *input.Score
+	}
+
+	v := validator.New()
+	if data.ValidateWordEntry(v, entry); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.WordLists.SetWord(r.Context(), list.ID, entry)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"word": data.Word{Entry: entry, ListID: list.ID}}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWordHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getEditableWordList(w, r)
+	if list == nil {
+		return
+	}
+
+	word, err := app.readStringParam(r, "word")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.WordLists.DeleteWord(r.Context(), list.ID, wordlist.Normalize(word))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "word successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) lookupWordsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.WordLookup
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.WordLookup.Pattern = wordlist.NormalizePattern(app.readString(qs, "pattern", ""))
+	input.WordLookup.MinScore = app.readInt(qs, "min_score", 0, v)
+	input.WordLookup.ListID = app.readInt(qs, "list", 0, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 100, v)
+	input.Filters.Sort = "-score"
+	input.Filters.SortSafeList = []string{"-score"}
+
+	data.ValidateWordLookup(v, input.WordLookup)
+	data.ValidateFilters(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if input.WordLookup.ListID != 0 {
+		list, err := app.models.WordLists.GetByID(r.Context(), input.WordLookup.ListID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if list == nil || !list.CanRead(user) {
+			v.AddError("list", "must be the house list or one of your own lists")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	words, metadata, err := app.models.WordLists.Lookup(r.Context(), user.ID, input.WordLookup, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"words": words, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
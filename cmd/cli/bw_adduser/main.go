@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"time"
@@ -32,7 +33,8 @@ func main() {
 	}
 	defer dbPool.Close()
 
-	models := data.NewModels(dbPool)
+	models := data.NewModels(dbPool, data.DefaultQueryTimeout, data.DefaultBulkTimeout)
+	ctx := context.Background()
 
 	user := &data.User{
 		Email:       email,
@@ -45,12 +47,12 @@ func main() {
 		panic(err)
 	}
 
-	err = models.Users.Insert(user)
+	err = models.Users.Insert(ctx, user)
 	if err != nil {
 		panic(err)
 	}
 
-	err = models.Permissions.AddForUser(user.ID, data.StandardPermissions...)
+	err = models.Permissions.AddForUser(ctx, user.ID, data.StandardPermissions...)
 	if err != nil {
 		panic(err)
 	}
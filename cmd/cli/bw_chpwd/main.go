@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"time"
@@ -33,9 +34,10 @@ func main() {
 	}
 	defer dbPool.Close()
 
-	models := data.NewModels(dbPool)
+	models := data.NewModels(dbPool, data.DefaultQueryTimeout, data.DefaultBulkTimeout)
+	ctx := context.Background()
 
-	user, err := models.Users.GetByEmail(email)
+	user, err := models.Users.GetByEmail(ctx, email)
 	if err != nil {
 		log.Fatalf("Error fetching user: %v", err)
 	}
@@ -50,7 +52,7 @@ func main() {
 		log.Fatalf("Error setting new password: %v", err)
 	}
 
-	err = models.Users.Update(user)
+	err = models.Users.Update(ctx, user)
 	if err != nil {
 		log.Fatalf("Error updating user password: %v", err)
 	}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/puzzlefile"
+)
+
+func main() {
+	var db data.DBConfig
+	var format string
+	var output string
+	var authorID int
+	var createdAfter string
+	var createdBefore string
+
+	flag.StringVar(&db.DSN, "db-dsn", "", "Postgresql DSN")
+	flag.StringVar(&format, "format", puzzlefile.FormatNDJSON, "Export format (ndjson|ipuz)")
+	flag.StringVar(&output, "output", "", "File to write the export to (default stdout)")
+	flag.IntVar(&authorID, "author", 0, "Only export puzzles by this author ID")
+	flag.StringVar(&createdAfter, "created-after", "", "Only export puzzles created at or after this RFC3339 time")
+	flag.StringVar(&createdBefore, "created-before", "", "Only export puzzles created before this RFC3339 time")
+	flag.Parse()
+
+	db.MaxOpenConns = 25
+	db.MinConns = 4
+	db.MaxIdleTime = 15 * time.Minute
+
+	filter := data.PuzzleExportFilter{AuthorID: authorID}
+	var err error
+	if createdAfter != "" {
+		filter.CreatedAfter, err = time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			log.Fatalf("Invalid -created-after: %v", err)
+		}
+	}
+	if createdBefore != "" {
+		filter.CreatedBefore, err = time.Parse(time.RFC3339, createdBefore)
+		if err != nil {
+			log.Fatalf("Invalid -created-before: %v", err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("Error creating output file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	ew, err := puzzlefile.NewExportWriter(bw, format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbPool, err := data.OpenDB(db)
+	if err != nil {
+		log.Fatalf("Error opening database: %v", err)
+	}
+	defer dbPool.Close()
+
+	models := data.NewModels(dbPool, data.DefaultQueryTimeout, data.DefaultBulkTimeout)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	count := 0
+	err = models.Puzzles.Export(ctx, filter, func(puzzle *data.Puzzle) error {
+		count++
+		return ew.Write(puzzle)
+	})
+	if err != nil {
+		log.Fatalf("Error exporting puzzles: %v", err)
+	}
+	err = ew.Close()
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		log.Fatalf("Error writing export: %v", err)
+	}
+
+	log.Printf("Exported %d puzzles.", count)
+}
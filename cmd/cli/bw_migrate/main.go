@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+)
+
+const usage = `Usage: bw_migrate -db-dsn=DSN COMMAND
+
+Commands:
+  up             apply all migrations newer than the database's version
+  down [N]       revert the newest N migrations (default 1)
+  version        print the database's version and the latest migration's
+  force VERSION  record the database as being at VERSION without running
+                 anything, after fixing a failed migration by hand
+`
+
+func main() {
+	var db data.DBConfig
+
+	flag.StringVar(&db.DSN, "db-dsn", "", "Postgresql DSN")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	db.MaxOpenConns = 2
+	db.MinConns = 1
+	db.MaxIdleTime = 15 * time.Minute
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	dbPool, err := data.OpenDB(db)
+	if err != nil {
+		log.Fatalf("Error opening database: %v", err)
+	}
+	defer dbPool.Close()
+
+	// stop at the next statement on ctrl-c, leaving the lock released
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch command {
+	case "up":
+		applied, err := data.MigrateUp(ctx, dbPool)
+		for _, m := range applied {
+			log.Printf("Applied %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error migrating up: %v", err)
+		}
+		if len(applied) == 0 {
+			log.Printf("No migrations to apply")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of migrations %q", args[0])
+			}
+		}
+		reverted, err := data.MigrateDown(ctx, dbPool, steps)
+		for _, m := range reverted {
+			log.Printf("Reverted %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error migrating down: %v", err)
+		}
+		if len(reverted) == 0 {
+			log.Printf("No migrations to revert")
+		}
+
+	case "version":
+		version, dirty, err := data.MigrationVersion(ctx, dbPool)
+		if err != nil {
+			log.Fatalf("Error reading version: %v", err)
+		}
+		fmt.Printf("database: %d", version)
+		if dirty {
+			fmt.Print(" (dirty)")
+		}
+		fmt.Printf("\nlatest: %d\n", data.SchemaVersion)
+
+	case "force":
+		if len(args) != 1 {
+			log.Fatal("force needs the VERSION to record")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatalf("Invalid version %q", args[0])
+		}
+		err = data.ForceMigrationVersion(ctx, dbPool, version)
+		if err != nil {
+			log.Fatalf("Error forcing version: %v", err)
+		}
+		log.Printf("Database recorded as being at version %d", version)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
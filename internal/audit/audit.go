@@ -0,0 +1,115 @@
+// Package audit records privileged actions, such as logins, permission
+// changes and edits to puzzles and users, in the audit_events table.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/tomasen/realip"
+)
+
+const (
+	Login              = "auth.login"
+	LoginFailed        = "auth.login_failed"
+	PermissionsGranted = "permissions.grant"
+
+	PuzzleCreated     = "puzzle.create"
+	PuzzleUpdated     = "puzzle.update"
+	PuzzlePublished   = "puzzle.publish"
+	PuzzleUnpublished = "puzzle.unpublish"
+	PuzzleDeleted     = "puzzle.delete"
+
+	UserCreated         = "user.create"
+	UserUpdated         = "user.update"
+	UserDeleted         = "user.delete"
+	UserPasswordReset   = "user.password_reset"
+	UserSessionsRevoked = "user.sessions_revoked"
+)
+
+const (
+	TargetPuzzle = "puzzle"
+	TargetUser   = "user"
+)
+
+// Event describes an action to record. Actor is nil when nobody is logged in,
+// as for a failed login, or when who it was mustn't be kept. Anonymous leaves
+// out the client IP too, for someone whose personal data has been erased.
+type Event struct {
+	Action     string
+	Actor      *data.User
+	TargetType string
+	TargetID   int
+	Details    map[string]any
+	Anonymous  bool
+}
+
+type Recorder struct {
+	events data.AuditRepository
+	logger *slog.Logger
+}
+
+func New(events data.AuditRepository, logger *slog.Logger) *Recorder {
+	return &Recorder{events: events, logger: logger}
+}
+
+// Record saves an event along with the client IP of the request. The action
+// has already happened by the time it's recorded, so a failure to save the
+// event is logged rather than returned.
+func (rec *Recorder) Record(r *http.Request, e Event) {
+	event := &data.AuditEvent{
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Details:    e.Details,
+	}
+	if !e.Anonymous {
+		event.IP = realip.FromRequest(r)
+	}
+	if e.Actor != nil && !e.Actor.IsAnonymous() {
+		event.ActorID = e.Actor.ID
+		event.ActorEmail = e.Actor.Email
+	}
+
+	err := rec.events.Insert(context.WithoutCancel(r.Context()), event)
+	if err != nil {
+		rec.logger.Error(err.Error(), "action", e.Action, "target_type", e.TargetType, "target_id", e.TargetID)
+	}
+}
+
+// Diff compares the JSON encodings of two values and returns the top-level
+// fields that differ as {"field": {"from": old, "to": new}}. Either value may
+// be nil, to record everything about something created or deleted.
+func Diff(before, after any) map[string]any {
+	from, to := fields(before), fields(after)
+	changes := map[string]any{}
+	for key, oldValue := range from {
+		if newValue, ok := to[key]; !ok || !reflect.DeepEqual(oldValue, newValue) {
+			changes[key] = map[string]any{"from": oldValue, "to": newValue}
+		}
+	}
+	for key, newValue := range to {
+		if _, ok := from[key]; !ok {
+			changes[key] = map[string]any{"from": nil, "to": newValue}
+		}
+	}
+	return changes
+}
+
+func fields(v any) map[string]any {
+	m := map[string]any{}
+	if v == nil {
+		return m
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return m
+	}
+	// values that don't encode as objects have no fields to compare
+	_ = json.Unmarshal(js, &m)
+	return m
+}
@@ -0,0 +1,176 @@
+package autofill
+
+import (
+	"math/bits"
+	"sort"
+)
+
+type Word struct {
+	Text  string
+	Score int
+}
+
+// Dictionary indexes words by length and by the letter at each position so
+// the candidates for a partly filled entry can be found by intersecting
+// bitsets instead of scanning the whole list.
+type Dictionary struct {
+	byLength map[int]*lengthIndex
+	scores   map[string]int
+}
+
+type lengthIndex struct {
+	words []Word
+	// letters[pos][letter] has a bit set for every word with that letter at
+	// that position.
+	letters [][26]bitset
+	all     bitset
+}
+
+// NewDictionary builds a dictionary from upper case A-Z words, skipping any
+// others. Words are ordered by descending score so that candidates come out
+// best first.
+func NewDictionary(words []Word) *Dictionary {
+	sorted := make([]Word, len(words))
+	copy(sorted, words)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score > sorted[j].Score
+		}
+		return sorted[i].Text < sorted[j].Text
+	})
+
+	d := &Dictionary{byLength: make(map[int]*lengthIndex), scores: make(map[string]int, len(sorted))}
+	for _, w := range sorted {
+		if _, exists := d.scores[w.Text]; exists || !validWord(w.Text) {
+			continue
+		}
+		d.scores[w.Text] = w.Score
+		idx, ok := d.byLength[len(w.Text)]
+		if !ok {
+			idx = &lengthIndex{}
+			d.byLength[len(w.Text)] = idx
+		}
+		idx.words = append(idx.words, w)
+	}
+
+	for length, idx := range d.byLength {
+		n := len(idx.words)
+		idx.all = newBitset(n)
+		idx.letters = make([][26]bitset, length)
+		for pos := range idx.letters {
+			for l := range idx.letters[pos] {
+				idx.letters[pos][l] = newBitset(n)
+			}
+		}
+		for i, w := range idx.words {
+			idx.all.set(i)
+			for pos := 0; pos < length; pos++ {
+				idx.letters[pos][w.Text[pos]-'A'].set(i)
+			}
+		}
+	}
+	return d
+}
+
+func validWord(word string) bool {
+	if word == "" {
+		return false
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'A' || word[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of words in the dictionary.
+func (d *Dictionary) Len() int {
+	return len(d.scores)
+}
+
+// candidates returns the words fitting a pattern of letters and blanks.
+func (d *Dictionary) candidates(pattern []byte, blank byte) (*lengthIndex, bitset) {
+	idx, ok := d.byLength[len(pattern)]
+	if !ok {
+		return nil, nil
+	}
+	var result bitset
+	for pos, b := range pattern {
+		if b == blank {
+			continue
+		}
+		if b < 'A' || b > 'Z' {
+			return idx, newBitset(len(idx.words))
+		}
+		if result == nil {
+			result = idx.letters[pos][b-'A'].clone()
+		} else {
+			result.and(idx.letters[pos][b-'A'])
+		}
+	}
+	if result == nil {
+		return idx, idx.all
+	}
+	return idx, result
+}
+
+// contains reports whether the dictionary holds a complete word and returns
+// its score.
+func (d *Dictionary) contains(word string) (int, bool) {
+	score, ok := d.scores[word]
+	return score, ok
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (uint(i) % 64)
+}
+
+func (b bitset) clone() bitset {
+	c := make(bitset, len(b))
+	copy(c, b)
+	return c
+}
+
+func (b bitset) and(other bitset) {
+	for i := range b {
+		b[i] &= other[i]
+	}
+}
+
+func (b bitset) any() bool {
+	for _, w := range b {
+		if w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b bitset) count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// each calls fn with the index of every set bit in ascending order until fn
+// returns false.
+func (b bitset) each(fn func(i int) bool) {
+	for wi, w := range b {
+		for w != 0 {
+			tz := bits.TrailingZeros64(w)
+			if !fn(wi*64 + tz) {
+				return
+			}
+			w &= w - 1
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package autofill
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+var ErrNoFill = errors.New("no fill exists for this grid with the available words")
+
+type Options struct {
+	// MaxResults is the number of complete fills to look for.
+	MaxResults int
+}
+
+type Result struct {
+	Grid *grid.Grid
+	// Score is the average dictionary score of the grid's entries. Entries
+	// that were already filled and aren't in the dictionary count as zero.
+	Score float64
+}
+
+type solver struct {
+	ctx       context.Context
+	grid      *grid.Grid
+	dict      *Dictionary
+	entries   []grid.Entry
+	crossings [][]int
+	filled    []bool
+	used      map[string]bool
+	results   []Result
+	max       int
+	nodes     int
+	err       error
+}
+
+// Fill completes the empty squares of a grid with words from the dictionary.
+// It treats each entry as a variable whose domain is the words fitting its
+// current letters, always filling the most constrained entry next, and after
+// each placement checks that every crossing entry can still be filled before
+// going deeper. No word is used twice in a fill.
+//
+// Fill stops once opts.MaxResults fills have been found, the search is
+// exhausted, or ctx is done. Fills found before ctx is done are returned
+// without an error; if none were found the context's error is returned.
+func Fill(ctx context.Context, g *grid.Grid, dict *Dictionary, opts Options) ([]Result, error) {
+	s := &solver{
+		ctx:     ctx,
+		grid:    g.Clone(),
+		dict:    dict,
+		entries: g.Entries(),
+		used:    make(map[string]bool),
+		max:     max(opts.MaxResults, 1),
+	}
+	s.filled = make([]bool, len(s.entries))
+
+	// map every square to the entries passing through it to find crossings
+	cellEntries := make([][]int, g.Width*g.Height)
+	for i, e := range s.entries {
+		for j := 0; j < e.Length; j++ {
+			r, c := e.Cell(j)
+			cellEntries[r*g.Width+c] = append(cellEntries[r*g.Width+c], i)
+		}
+	}
+	s.crossings = make([][]int, len(s.entries))
+	for i, e := range s.entries {
+		for j := 0; j < e.Length; j++ {
+			r, c := e.Cell(j)
+			for _, other := range cellEntries[r*g.Width+c] {
+				if other != i {
+					s.crossings[i] = append(s.crossings[i], other)
+				}
+			}
+		}
+	}
+
+	// entries the constructor already completed are kept as they are, even
+	// when they aren't in the dictionary
+	for i, e := range s.entries {
+		word := s.grid.Word(e)
+		if !strings.ContainsRune(word, rune(grid.Blank)) {
+			s.filled[i] = true
+			s.used[word] = true
+		}
+	}
+
+	s.solve()
+
+	if len(s.results) > 0 {
+		return s.results, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return nil, ErrNoFill
+}
+
+// solve fills one entry and recurses. It returns true when the search should
+// stop.
+func (s *solver) solve() bool {
+	s.nodes++
+	if s.nodes%64 == 0 {
+		if err := s.ctx.Err(); err != nil {
+			s.err = err
+			return true
+		}
+	}
+
+	best := -1
+	var bestIndex *lengthIndex
+	var bestCandidates bitset
+	bestCount := 0
+	for i, e := range s.entries {
+		if s.filled[i] {
+			continue
+		}
+		index, candidates := s.dict.candidates([]byte(s.grid.Word(e)), grid.Blank)
+		if index == nil {
+			return false
+		}
+		count := candidates.count()
+		if count == 0 {
+			return false
+		}
+		if best == -1 || count < bestCount {
+			best, bestIndex, bestCandidates, bestCount = i, index, candidates, count
+		}
+	}
+
+	if best == -1 {
+		s.record()
+		return len(s.results) >= s.max
+	}
+
+	entry := s.entries[best]
+	s.filled[best] = true
+	stop := false
+	bestCandidates.each(func(i int) bool {
+		word := bestIndex.words[i].Text
+		if s.used[word] {
+			return true
+		}
+		changed := s.write(entry, word)
+		s.used[word] = true
+		if s.crossingsFillable(best) && s.solve() {
+			stop = true
+		}
+		delete(s.used, word)
+		s.undo(changed)
+		return !stop
+	})
+	s.filled[best] = false
+	return stop
+}
+
+// write places a word in an entry and returns the squares that were blank
+// before so the placement can be undone.
+func (s *solver) write(e grid.Entry, word string) []int {
+	var changed []int
+	for i := 0; i < e.Length; i++ {
+		r, c := e.Cell(i)
+		if s.grid.At(r, c) == grid.Blank {
+			changed = append(changed, r*s.grid.Width+c)
+			s.grid.Set(r, c, word[i])
+		}
+	}
+	return changed
+}
+
+func (s *solver) undo(changed []int) {
+	for _, cell := range changed {
+		s.grid.Cells[cell] = grid.Blank
+	}
+}
+
+// crossingsFillable reports whether every unfilled entry crossing the given
+// one still has at least one candidate.
+func (s *solver) crossingsFillable(entry int) bool {
+	for _, other := range s.crossings[entry] {
+		if s.filled[other] {
+			continue
+		}
+		_, candidates := s.dict.candidates([]byte(s.grid.Word(s.entries[other])), grid.Blank)
+		if !candidates.any() {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *solver) record() {
+	total := 0
+	for _, e := range s.entries {
+		score, _ := s.dict.contains(s.grid.Word(e))
+		total += score
+	}
+	result := Result{Grid: s.grid.Clone()}
+	if len(s.entries) > 0 {
+		result.Score = float64(total) / float64(len(s.entries))
+	}
+	s.results = append(s.results, result)
+}
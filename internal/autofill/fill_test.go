@@ -0,0 +1,154 @@
+package autofill
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+func newDictionary(texts ...string) *Dictionary {
+	words := make([]Word, len(texts))
+	for i, text := range texts {
+		words[i] = Word{Text: text, Score: 50}
+	}
+	return NewDictionary(words)
+}
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    []string
+		words   []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "Started",
+			rows:  []string{"CAT", ".#.", "..."},
+			words: []string{"CAT", "COW", "TOE", "WOE", "CUB"},
+			want:  []string{"CAT", "O#O", "WOE"},
+		},
+		{
+			name:  "Already complete with unknown words",
+			rows:  []string{"XYZ", "X#X", "XYZ"},
+			words: []string{"CAT"},
+			want:  []string{"XYZ", "X#X", "XYZ"},
+		},
+		{
+			name:    "No fitting words",
+			rows:    []string{"CAT", ".#.", "..."},
+			words:   []string{"CAT", "COW", "TOE"},
+			wantErr: ErrNoFill,
+		},
+		{
+			name:    "No words of the right length",
+			rows:    []string{"....", "....", "....", "...."},
+			words:   []string{"CAT", "COW"},
+			wantErr: ErrNoFill,
+		},
+		{
+			name:    "Words can't repeat",
+			rows:    []string{"..", ".."},
+			words:   []string{"AA"},
+			wantErr: ErrNoFill,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := grid.Parse(tt.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := Fill(context.Background(), g, newDictionary(tt.words...), Options{MaxResults: 1})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v; want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d results; want 1", len(results))
+			}
+			if got := results[0].Grid.Rows(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got rows %q; want %q", got, tt.want)
+			}
+			if got := g.Rows(); !reflect.DeepEqual(got, tt.rows) {
+				t.Errorf("filling changed the original grid to %q", got)
+			}
+		})
+	}
+}
+
+func TestFillResults(t *testing.T) {
+	g, err := grid.Parse([]string{"...", ".#.", "..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the grid can be filled with the words either way round
+	dict := newDictionary("CAT", "COW", "TOE", "WOE")
+
+	results, err := Fill(context.Background(), g, dict, Options{MaxResults: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results; want 2", len(results))
+	}
+	for _, result := range results {
+		used := map[string]bool{}
+		for _, e := range result.Grid.Entries() {
+			word := result.Grid.Word(e)
+			if _, ok := dict.contains(word); !ok || used[word] {
+				t.Errorf("fill %q uses %q, which isn't in the dictionary or is repeated", result.Grid.Rows(), word)
+			}
+			used[word] = true
+		}
+		if result.Score != 50 {
+			t.Errorf("got score %v; want 50", result.Score)
+		}
+	}
+}
+
+func TestFillDeadline(t *testing.T) {
+	// every across word has an even number of Bs and every down word an odd
+	// number, so with an odd number of columns the grid can't be filled, but
+	// that only shows once a fill is nearly complete and the search takes
+	// far longer than the deadline
+	g, err := grid.Parse([]string{".......", ".......", ".......", ".......", ".......", "......."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var words []Word
+	for _, length := range []int{g.Width, g.Height} {
+		for i := 0; i < 1<<length; i++ {
+			word := make([]byte, length)
+			bs := 0
+			for j := range word {
+				word[j] = 'A' + byte(i>>j&1)
+				bs += i >> j & 1
+			}
+			if (length == g.Width) == (bs%2 == 0) {
+				words = append(words, Word{Text: string(word), Score: 50})
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = Fill(ctx, g, NewDictionary(words), Options{MaxResults: 1})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v; want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fill took %v to give up", elapsed)
+	}
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/validator"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditEvent records a privileged action. ActorEmail is copied from the actor
+// when the event is recorded, and cleared along with the IP when the actor's
+// account is deleted.
+type AuditEvent struct {
+	ID         int64          `json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Action     string         `json:"action"`
+	ActorID    int            `json:"actor_id,omitempty"`
+	ActorEmail string         `json:"actor_email,omitempty"`
+	TargetType string         `json:"target_type,omitempty"`
+	TargetID   int            `json:"target_id,omitempty"`
+	IP         string         `json:"ip"`
+	Details    map[string]any `json:"details"`
+}
+
+type AuditFilter struct {
+	ActorID       int
+	Action        string
+	TargetType    string
+	TargetID      int
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+var AuditSortSafeList = []string{"created_at", "-created_at"}
+
+func ValidateAuditFilter(v *validator.Validator, f AuditFilter) {
+	v.Check(f.ActorID >= 0, "actor_id", "must not be negative")
+	v.Check(f.TargetID >= 0, "target_id", "must not be negative")
+	v.Check(f.TargetID == 0 || f.TargetType != "", "target_type", "must be provided with target_id")
+	v.Check(f.CreatedBefore.IsZero() || f.CreatedAfter.Before(f.CreatedBefore), "created_before", "must be after created_after")
+}
+
+type AuditModel struct {
+	DB      *pgxpool.Pool
+	Timeout time.Duration
+}
+
+// Insert saves an event. The actor is looked up rather than referenced
+// directly so that an event can still be saved after its actor has deleted
+// their own account.
+func (m AuditModel) Insert(ctx context.Context, event *AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (action, actor_id, actor_email, target_type, target_id, ip, details)
+		VALUES ($1, (SELECT id FROM users WHERE id = $2), $3, $4, NULLIF($5, 0), $6, $7)
+		RETURNING id, created_at`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	if event.Details == nil {
+		event.Details = map[string]any{}
+	}
+	args := []any{event.Action, event.ActorID, event.ActorEmail, event.TargetType, event.TargetID, event.IP, event.Details}
+	return m.DB.QueryRow(ctx, query, args...).Scan(&event.ID, &event.CreatedAt)
+}
+
+func (m AuditModel) List(ctx context.Context, filter AuditFilter, filters Filters) ([]*AuditEvent, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, action, COALESCE(actor_id, 0), actor_email, target_type, COALESCE(target_id, 0), ip, details
+		FROM audit_events
+		WHERE (actor_id = $1 OR $1 = 0)
+		AND (action = $2 OR $2 = '')
+		AND (target_type = $3 OR $3 = '')
+		AND (target_id = $4 OR $4 = 0)
+		AND (created_at >= $5 OR $5 IS NULL)
+		AND (created_at < $6 OR $6 IS NULL)
+		ORDER BY %s %s, id %[2]s
+		LIMIT $7 OFFSET $8`, filters.sortColumn(), filters.sortDirection())
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	args := []any{
+		filter.ActorID,
+		filter.Action,
+		filter.TargetType,
+		filter.TargetID,
+		nullTime(filter.CreatedAfter),
+		nullTime(filter.CreatedBefore),
+		filters.limit(),
+		filters.offset(),
+	}
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	events := []*AuditEvent{}
+	totalRecords := 0
+	for rows.Next() {
+		var event AuditEvent
+		err := rows.Scan(
+			&totalRecords,
+			&event.ID,
+			&event.CreatedAt,
+			&event.Action,
+			&event.ActorID,
+			&event.ActorEmail,
+			&event.TargetType,
+			&event.TargetID,
+			&event.IP,
+			&event.Details,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		events = append(events, &event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return events, metadata, nil
+}
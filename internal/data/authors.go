@@ -0,0 +1,55 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Author is the public profile of a user who writes puzzles.
+type Author struct {
+	ID               int       `json:"id"`
+	DisplayName      string    `json:"display_name"`
+	Bio              string    `json:"bio"`
+	AvatarURL        string    `json:"avatar_url"`
+	PublishedPuzzles int       `json:"published_puzzles"`
+	JoinedAt         time.Time `json:"joined_at"`
+}
+
+type AuthorModel struct {
+	DB      *pgxpool.Pool
+	Timeout time.Duration
+}
+
+func (m AuthorModel) GetByID(ctx context.Context, id int) (*Author, error) {
+	query := `
+		SELECT u.id, u.display_name, u.bio, u.avatar_url, u.created_at,
+			(SELECT count(*) FROM puzzles p WHERE p.author_id = u.id AND p.published)
+		FROM users u
+		WHERE u.id = $1 AND u.activated`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	var author Author
+	err := m.DB.QueryRow(ctx, query, id).Scan(
+		&author.ID,
+		&author.DisplayName,
+		&author.Bio,
+		&author.AvatarURL,
+		&author.JoinedAt,
+		&author.PublishedPuzzles,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &author, nil
+}
@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/wordlist"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ClueUse is a clue from a puzzle along with the puzzle it appeared in.
+type ClueUse struct {
+	ID          int        `json:"id"`
+	PuzzleID    int        `json:"puzzle_id"`
+	PuzzleTitle string     `json:"puzzle_title"`
+	Direction   string     `json:"direction"`
+	Number      int        `json:"number"`
+	Clue        string     `json:"clue"`
+	Answer      string     `json:"answer"`
+	Published   bool       `json:"published"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+var ClueSortSafeList = []string{"published_at", "-published_at", "title", "-title"}
+
+type ClueModel struct {
+	DB      *pgxpool.Pool
+	Timeout time.Duration
+}
+
+// setClues replaces the rows of the clues table for a puzzle with the clues in
+// its content, so that clues can be queried without unpacking every
+// puzzle's JSON. Clues whose number isn't an integer are skipped.
+func setClues(ctx context.Context, tx pgx.Tx, puzzleID int, content PuzzleData) error {
+	_, err := tx.Exec(ctx, `DELETE FROM clues WHERE puzzle_id = $1`, puzzleID)
+	if err != nil {
+		return err
+	}
+
+	var (
+		directions []string
+		numbers    []int
+		clues      []string
+		answers    []string
+	)
+	add := func(direction string, entries map[string]ClueData) {
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			// older puzzles may have keys that would overflow or collide
+			if !validClueNumber(key) {
+				continue
+			}
+			number, _ := strconv.Atoi(key)
+			directions = append(directions, direction)
+			numbers = append(numbers, number)
+			clues = append(clues, entries[key].Clue)
+			answers = append(answers, wordlist.Normalize(entries[key].Answer))
+		}
+	}
+	add("across", content.Across)
+	add("down", content.Down)
+	if len(numbers) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO clues (puzzle_id, direction, number, clue, answer)
+		SELECT $1, c.direction, c.number, c.clue, c.answer
+		FROM unnest($2::text[], $3::int[], $4::text[], $5::text[]) AS c(direction, number, clue, answer)`,
+		puzzleID, directions, numbers, clues, answers)
+	return err
+}
+
+// GetByAnswer returns every clue written for an answer. When publishedOnly is
+// set, clues from unpublished puzzles are left out.
+func (m ClueModel) GetByAnswer(ctx context.Context, answer string, publishedOnly bool, filters Filters) ([]*ClueUse, Metadata, error) {
+	column := "COALESCE(p.published_at, p.created_at)"
+	if filters.sortColumn() == "title" {
+		column = "p.title"
+	}
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), c.id, c.puzzle_id, p.title, c.direction, c.number, c.clue, c.answer, p.published, p.published_at, p.created_at
+		FROM clues c
+		INNER JOIN puzzles p ON c.puzzle_id = p.id
+		WHERE c.answer = $1 AND (p.published OR NOT $2)
+		ORDER BY %s %s, c.id DESC
+		LIMIT $3 OFFSET $4`, column, filters.sortDirection())
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, answer, publishedOnly, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	uses := []*ClueUse{}
+	totalRecords := 0
+	for rows.Next() {
+		var use ClueUse
+		err := rows.Scan(
+			&totalRecords,
+			&use.ID,
+			&use.PuzzleID,
+			&use.PuzzleTitle,
+			&use.Direction,
+			&use.Number,
+			&use.Clue,
+			&use.Answer,
+			&use.Published,
+			&use.PublishedAt,
+			&use.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		uses = append(uses, &use)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return uses, metadata, nil
+}
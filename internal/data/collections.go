@@ -0,0 +1,376 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ggetzie/badwords_be/internal/validator"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Collection struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CoverURL    string    `json:"cover_url"`
+	Published   bool      `json:"published"`
+	OwnerID     int       `json:"owner_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	PuzzleCount int       `json:"puzzle_count"`
+	Version     int       `json:"-"`
+}
+
+type CollectionModel struct {
+	DB      *pgxpool.Pool
+	Timeout time.Duration
+}
+
+var CollectionSortSafeList = []string{
+	"id", "title", "created_at", "updated_at",
+	"-id", "-title", "-created_at", "-updated_at",
+}
+
+func ValidateCollection(v *validator.Validator, collection *Collection) {
+	v.Check(collection.Title != "", "title", "must be provided")
+	v.Check(utf8.RuneCountInString(collection.Title) <= 200, "title", "must not be more than 200 characters long")
+	v.Check(utf8.RuneCountInString(collection.Description) <= 2000, "description", "must not be more than 2000 characters long")
+	if collection.CoverURL != "" {
+		v.Check(validator.ValidURL(collection.CoverURL), "cover_url", "must be a valid http or https URL")
+		v.Check(len(collection.CoverURL) <= 2000, "cover_url", "must not be more than 2000 bytes long")
+	}
+}
+
+func ValidateCollectionPuzzleIDs(v *validator.Validator, puzzleIDs []int) {
+	v.Check(len(puzzleIDs) <= 500, "puzzle_ids", "must not contain more than 500 puzzles")
+	v.Check(validator.Unique(puzzleIDs), "puzzle_ids", "must not contain duplicate values")
+	for _, id := range puzzleIDs {
+		v.Check(id > 0, "puzzle_ids", "must only contain positive integers")
+	}
+}
+
+// Insert adds a collection holding the given puzzles, in the given order.
+func (m CollectionModel) Insert(ctx context.Context, collection *Collection, puzzleIDs []int) error {
+	query := `
+		INSERT INTO collections (title, description, cover_url, published, owner_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at, version`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	args := []any{
+		collection.Title,
+		collection.Description,
+		collection.CoverURL,
+		collection.Published,
+		collection.OwnerID,
+	}
+	err = tx.QueryRow(ctx, query, args...).Scan(
+		&collection.ID, &collection.CreatedAt, &collection.UpdatedAt, &collection.Version)
+	if err != nil {
+		return err
+	}
+
+	err = setCollectionPuzzles(ctx, tx, collection.ID, puzzleIDs)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	collection.PuzzleCount = len(puzzleIDs)
+	return nil
+}
+
+func (m CollectionModel) GetByID(ctx context.Context, id int) (*Collection, error) {
+	query := `
+		SELECT c.id, c.title, c.description, c.cover_url, c.published, COALESCE(c.owner_id, 0), c.created_at, c.updated_at, c.version,
+			(SELECT count(*) FROM collection_puzzles cp WHERE cp.collection_id = c.id)
+		FROM collections c
+		WHERE c.id = $1`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	var collection Collection
+	err := m.DB.QueryRow(ctx, query, id).Scan(
+		&collection.ID,
+		&collection.Title,
+		&collection.Description,
+		&collection.CoverURL,
+		&collection.Published,
+		&collection.OwnerID,
+		&collection.CreatedAt,
+		&collection.UpdatedAt,
+		&collection.Version,
+		&collection.PuzzleCount,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &collection, nil
+}
+
+func (m CollectionModel) Update(ctx context.Context, collection *Collection) error {
+	query := `
+		UPDATE collections
+		SET title = $1, description = $2, cover_url = $3, published = $4, updated_at = NOW(), version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version, updated_at`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	args := []any{
+		collection.Title,
+		collection.Description,
+		collection.CoverURL,
+		collection.Published,
+		collection.ID,
+		collection.Version,
+	}
+	err := m.DB.QueryRow(ctx, query, args...).Scan(&collection.Version, &collection.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m CollectionModel) Delete(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM collections
+		WHERE id = $1`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m CollectionModel) List(ctx context.Context, published1, published2 bool, filters Filters) ([]*Collection, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), c.id, c.title, c.description, c.cover_url, c.published, COALESCE(c.owner_id, 0), c.created_at, c.updated_at, c.version,
+			(SELECT count(*) FROM collection_puzzles cp WHERE cp.collection_id = c.id)
+		FROM collections c
+		WHERE (c.published = $1 OR c.published = $2)
+		ORDER BY c.%s %s, c.id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, published1, published2, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	collections := []*Collection{}
+	totalRecords := 0
+	for rows.Next() {
+		var collection Collection
+		err := rows.Scan(
+			&totalRecords,
+			&collection.ID,
+			&collection.Title,
+			&collection.Description,
+			&collection.CoverURL,
+			&collection.Published,
+			&collection.OwnerID,
+			&collection.CreatedAt,
+			&collection.UpdatedAt,
+			&collection.Version,
+			&collection.PuzzleCount,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		collections = append(collections, &collection)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return collections, metadata, nil
+}
+
+// GetAllForOwner returns every collection the user owns, published or not.
+func (m CollectionModel) GetAllForOwner(ctx context.Context, ownerID int) ([]*Collection, error) {
+	query := `
+		SELECT c.id, c.title, c.description, c.cover_url, c.published, COALESCE(c.owner_id, 0), c.created_at, c.updated_at, c.version,
+			(SELECT count(*) FROM collection_puzzles cp WHERE cp.collection_id = c.id)
+		FROM collections c
+		WHERE c.owner_id = $1
+		ORDER BY c.id`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*Collection{}
+	for rows.Next() {
+		var collection Collection
+		err := rows.Scan(
+			&collection.ID,
+			&collection.Title,
+			&collection.Description,
+			&collection.CoverURL,
+			&collection.Published,
+			&collection.OwnerID,
+			&collection.CreatedAt,
+			&collection.UpdatedAt,
+			&collection.Version,
+			&collection.PuzzleCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, &collection)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// GetPuzzles returns the puzzles in a collection in their collection order.
+// When publishedOnly is set, puzzles that are not currently published are
+// left out.
+func (m CollectionModel) GetPuzzles(ctx context.Context, collectionID int, publishedOnly bool) ([]*Puzzle, error) {
+	query := `
+		SELECT p.id, p.title, p.width, p.height, p.created_at, p.updated_at, p.published, p.published_at, p.version, u.id, u.full_name, u.display_name, u.email,` + puzzleMetaColumns + `
+		FROM collection_puzzles cp
+		INNER JOIN puzzles p ON cp.puzzle_id = p.id
+		INNER JOIN users u ON p.author_id = u.id
+		WHERE cp.collection_id = $1 AND (p.published OR NOT $2)
+		ORDER BY cp.position`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, collectionID, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	puzzles := []*Puzzle{}
+	for rows.Next() {
+		puzzle := &Puzzle{}
+		err := rows.Scan(
+			&puzzle.ID,
+			&puzzle.Title,
+			&puzzle.Width,
+			&puzzle.Height,
+			&puzzle.CreatedAt,
+			&puzzle.UpdatedAt,
+			&puzzle.Published,
+			&puzzle.PublishedAt,
+			&puzzle.Version,
+			&puzzle.Author.ID,
+			&puzzle.Author.FullName,
+			&puzzle.Author.DisplayName,
+			&puzzle.Author.Email,
+			&puzzle.Tags,
+			&puzzle.Theme,
+			&puzzle.Notes,
+			&puzzle.Difficulty,
+			&puzzle.SolverDifficulty,
+			&puzzle.SolverVotes,
+		)
+		if err != nil {
+			return nil, err
+		}
+		puzzles = append(puzzles, puzzle)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return puzzles, nil
+}
+
+// setCollectionPuzzles replaces the membership of a collection with the
+// given puzzles, in the given order.
+func setCollectionPuzzles(ctx context.Context, tx pgx.Tx, collectionID int, puzzleIDs []int) error {
+	_, err := tx.Exec(ctx, `DELETE FROM collection_puzzles WHERE collection_id = $1`, collectionID)
+	if err != nil {
+		return err
+	}
+	if len(puzzleIDs) == 0 {
+		return nil
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO collection_puzzles (collection_id, puzzle_id, position)
+		SELECT $1, ids.puzzle_id, ids.position
+		FROM unnest($2::int[]) WITH ORDINALITY AS ids(puzzle_id, position)`, collectionID, puzzleIDs)
+	return err
+}
+
+// SetPuzzles replaces the membership of a collection with the given puzzles,
+// in the given order.
+func (m CollectionModel) SetPuzzles(ctx context.Context, collectionID int, puzzleIDs []int) error {
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = setCollectionPuzzles(ctx, tx, collectionID, puzzleIDs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE collections SET updated_at = NOW(), version = version + 1 WHERE id = $1`, collectionID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UnpublishedPuzzleIDs returns the IDs of the puzzles in a collection that are
+// not published.
+func (m CollectionModel) UnpublishedPuzzleIDs(ctx context.Context, collectionID int) ([]int, error) {
+	query := `
+		SELECT p.id
+		FROM collection_puzzles cp
+		INNER JOIN puzzles p ON cp.puzzle_id = p.id
+		WHERE cp.collection_id = $1 AND NOT p.published
+		ORDER BY cp.position`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
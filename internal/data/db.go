@@ -12,6 +12,34 @@ type DBConfig struct {
 	MaxOpenConns int
 	MinConns     int
 	MaxIdleTime  time.Duration
+	QueryTimeout time.Duration
+	BulkTimeout  time.Duration
+}
+
+// DefaultQueryTimeout is how long a query may run when no timeout is set.
+const DefaultQueryTimeout = 3 * time.Second
+
+// queryContext derives the context for a query from the caller's, so the
+// query is cancelled when the caller gives up, for example when the client
+// disconnects, and never runs for longer than timeout.
+func queryContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// DefaultBulkTimeout is how long an operation over many rows, such as a word
+// list upload, may run when no timeout is set.
+const DefaultBulkTimeout = 30 * time.Second
+
+// bulkContext is queryContext for operations over many rows, which are given
+// longer than a single query.
+func bulkContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultBulkTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 func OpenDB(cfg DBConfig) (*pgxpool.Pool, error) {
@@ -22,6 +50,7 @@ func OpenDB(cfg DBConfig) (*pgxpool.Pool, error) {
 	config.MaxConns = int32(cfg.MaxOpenConns)
 	config.MinConns = int32(cfg.MinConns)
 	config.MaxConnIdleTime = cfg.MaxIdleTime
+	config.ConnConfig.Tracer = queryTracer{}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	dbpool, err := pgxpool.NewWithConfig(context.Background(), config)
@@ -1,6 +1,9 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"strings"
 
 	"slices"
@@ -8,11 +11,14 @@ import (
 	"github.com/ggetzie/badwords_be/internal/validator"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type Filters struct {
 	Page         int
 	PageSize     int
 	Sort         string
 	SortSafeList []string
+	After        string
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -23,6 +29,11 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafeList...), "sort", "invalid sort value")
 
+	if f.After != "" {
+		c, err := decodeCursor(f.After)
+		v.Check(err == nil && c.Sort == f.Sort, "after", "must be a cursor returned for the same sort")
+		v.Check(f.Page == 1, "page", "must not be combined with after")
+	}
 }
 
 func (f Filters) sortColumn() string {
@@ -49,12 +60,43 @@ func (f Filters) offset() int {
 	return (f.Page - 1) * f.PageSize
 }
 
+// cursor identifies the last row of a page for keyset pagination. It is
+// handed to clients as an opaque string and records the sort it was created
+// for so that it can't be replayed against a different ordering.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	js, err := json.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID < 1 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
 type Metadata struct {
-	CurentPage   int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurentPage   int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
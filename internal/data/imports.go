@@ -0,0 +1,175 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	ImportPending = "pending"
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+// The outcomes of importing one file from an archive. A dry run reports
+// ImportFileValid where a real import would have created a puzzle.
+const (
+	ImportFileCreated   = "created"
+	ImportFileValid     = "valid"
+	ImportFileInvalid   = "invalid"
+	ImportFileDuplicate = "duplicate"
+	ImportFileSkipped   = "skipped"
+)
+
+// ImportStaleAfter is how long an import may stay pending or running before
+// it's assumed to have been interrupted, for example by a restart, and the
+// archive may be uploaded again.
+const ImportStaleAfter = time.Hour
+
+type ImportResult struct {
+	File     string            `json:"file"`
+	Status   string            `json:"status"`
+	PuzzleID int               `json:"puzzle_id,omitempty"`
+	Title    string            `json:"title,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// Import is a bulk upload of puzzle files, processed in the background. The
+// checksum of the archive lets a repeated upload find the earlier import.
+type Import struct {
+	ID         int            `json:"id"`
+	OwnerID    int            `json:"owner_id"`
+	Checksum   string         `json:"-"`
+	DryRun     bool           `json:"dry_run"`
+	Status     string         `json:"status"`
+	FileCount  int            `json:"file_count"`
+	Processed  int            `json:"processed"`
+	Results    []ImportResult `json:"results"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	FinishedAt *time.Time     `json:"finished_at"`
+}
+
+type ImportModel struct {
+	DB      *pgxpool.Pool
+	Timeout time.Duration
+}
+
+func (m ImportModel) Insert(ctx context.Context, imp *Import) error {
+	query := `
+		INSERT INTO imports (owner_id, checksum, dry_run, file_count)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	imp.Results = nonNil(imp.Results)
+	return m.DB.QueryRow(ctx, query, imp.OwnerID, imp.Checksum, imp.DryRun, imp.FileCount).Scan(&imp.ID, &imp.Status, &imp.CreatedAt)
+}
+
+const importColumns = `id, owner_id, checksum, dry_run, status, file_count, processed, results, error, created_at, finished_at`
+
+func scanImport(row pgx.Row) (*Import, error) {
+	var imp Import
+	err := row.Scan(
+		&imp.ID,
+		&imp.OwnerID,
+		&imp.Checksum,
+		&imp.DryRun,
+		&imp.Status,
+		&imp.FileCount,
+		&imp.Processed,
+		&imp.Results,
+		&imp.Error,
+		&imp.CreatedAt,
+		&imp.FinishedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &imp, nil
+}
+
+func (m ImportModel) GetByID(ctx context.Context, id int) (*Import, error) {
+	query := `
+		SELECT ` + importColumns + `
+		FROM imports
+		WHERE id = $1`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return scanImport(m.DB.QueryRow(ctx, query, id))
+}
+
+// GetAllForOwner returns every import the user has started, oldest first.
+func (m ImportModel) GetAllForOwner(ctx context.Context, ownerID int) ([]*Import, error) {
+	query := `
+		SELECT ` + importColumns + `
+		FROM imports
+		WHERE owner_id = $1
+		ORDER BY id`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports := []*Import{}
+	for rows.Next() {
+		imp, err := scanImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, imp)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// GetByChecksum returns the latest import of the same archive by the same
+// user that wasn't a dry run and either finished or is still in progress.
+// Failed imports and ones that have gone stale can be retried.
+func (m ImportModel) GetByChecksum(ctx context.Context, ownerID int, checksum string) (*Import, error) {
+	query := `
+		SELECT ` + importColumns + `
+		FROM imports
+		WHERE owner_id = $1 AND checksum = $2 AND NOT dry_run
+		AND (status = 'done' OR (status IN ('pending', 'running') AND created_at > $3))
+		ORDER BY id DESC
+		LIMIT 1`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return scanImport(m.DB.QueryRow(ctx, query, ownerID, checksum, time.Now().Add(-ImportStaleAfter)))
+}
+
+// Update saves the progress of an import, recording when it finished once
+// it's done or has failed.
+func (m ImportModel) Update(ctx context.Context, imp *Import) error {
+	query := `
+		UPDATE imports
+		SET status = $1, processed = $2, results = $3, error = $4,
+			finished_at = CASE WHEN $1 IN ('done', 'failed') THEN NOW() END
+		WHERE id = $5
+		RETURNING finished_at`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	args := []any{imp.Status, imp.Processed, nonNil(imp.Results), imp.Error, imp.ID}
+	return m.DB.QueryRow(ctx, query, args...).Scan(&imp.FinishedAt)
+}
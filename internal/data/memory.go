@@ -0,0 +1,1498 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/wordlist"
+)
+
+// NewMemoryModels returns models that are kept in memory instead of
+// PostgreSQL, for tests and for running the API without a database. They
+// follow the same rules as the database: edit conflicts, duplicate emails,
+// token expiry, the placeholder author for deleted users' puzzles and
+// collections, the cascading deletes of a user's lists and imports, and the
+// scrubbing of their personal data from the audit log.
+// Puzzle searches match the query as a substring of the title or description
+// rather than using full text search, and sorting by relevance sorts by
+// creation date.
+func NewMemoryModels() Models {
+	s := &memoryStore{
+		users:             map[int]*User{},
+		tokens:            map[string]*Token{},
+		permissions:       map[int]Permissions{},
+		puzzles:           map[int]*Puzzle{},
+		votes:             map[int]map[int]DifficultyVote{},
+		clues:             map[int][]*ClueUse{},
+		collections:       map[int]*Collection{},
+		collectionPuzzles: map[int][]int{},
+		wordLists:         map[int]*WordList{},
+		words:             map[int]map[string]wordlist.Entry{},
+		imports:           map[int]*Import{},
+	}
+	s.nextUserID++
+	s.users[s.nextUserID] = &User{
+		ID:          s.nextUserID,
+		Email:       DeletedUserEmail,
+		DisplayName: "Former contributor",
+		CreatedAt:   time.Now(),
+		Version:     1,
+	}
+	// the shared house list is the one without an owner
+	now := time.Now()
+	s.nextListID++
+	s.wordLists[s.nextListID] = &WordList{ID: s.nextListID, Name: "House", Shared: true, CreatedAt: now, UpdatedAt: now}
+
+	return Models{
+		Users:       memoryUsers{s},
+		Tokens:      memoryTokens{s},
+		Permissions: memoryPermissions{s},
+		Puzzles:     memoryPuzzles{s},
+		Authors:     memoryAuthors{s},
+		Tags:        memoryTags{s},
+		Collections: memoryCollections{s},
+		WordLists:   memoryWordLists{s},
+		Clues:       memoryClues{s},
+		Imports:     memoryImports{s},
+		Audit:       memoryAudit{s},
+	}
+}
+
+// memoryStore holds every table behind one lock so that operations spanning
+// several of them, like deleting a user, are atomic. Records are copied in
+// and out so callers can't change them without going through the models.
+type memoryStore struct {
+	mu sync.Mutex
+
+	nextUserID       int
+	nextPuzzleID     int
+	nextClueID       int
+	nextCollectionID int
+	nextListID       int
+	nextImportID     int
+	nextEventID      int64
+
+	users       map[int]*User
+	tokens      map[string]*Token
+	permissions map[int]Permissions
+	puzzles     map[int]*Puzzle
+	// difficulty votes by puzzle and then user
+	votes map[int]map[int]DifficultyVote
+	// the clues of each puzzle, without the puzzle's details
+	clues       map[int][]*ClueUse
+	collections map[int]*Collection
+	// the puzzle IDs in each collection, in collection order
+	collectionPuzzles map[int][]int
+	wordLists         map[int]*WordList
+	// the words in each list, by word
+	words   map[int]map[string]wordlist.Entry
+	imports map[int]*Import
+	events  []*AuditEvent
+}
+
+func (s *memoryStore) emailTaken(email string, exceptID int) bool {
+	for _, u := range s.users {
+		if u.Email == email && u.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// deletePuzzle removes a puzzle along with its votes, clues and places in
+// collections.
+func (s *memoryStore) deletePuzzle(id int) {
+	delete(s.puzzles, id)
+	delete(s.votes, id)
+	delete(s.clues, id)
+	for collectionID, puzzleIDs := range s.collectionPuzzles {
+		s.collectionPuzzles[collectionID] = slices.DeleteFunc(puzzleIDs, func(puzzleID int) bool {
+			return puzzleID == id
+		})
+	}
+}
+
+// setClues replaces the clues of a puzzle with the ones in its content,
+// skipping clues whose number isn't an integer.
+func (s *memoryStore) setClues(puzzleID int, content PuzzleData) {
+	var clues []*ClueUse
+	add := func(direction string, entries map[string]ClueData) {
+		for _, key := range slices.Sorted(maps.Keys(entries)) {
+			if !validClueNumber(key) {
+				continue
+			}
+			number, _ := strconv.Atoi(key)
+			s.nextClueID++
+			clues = append(clues, &ClueUse{
+				ID:        s.nextClueID,
+				PuzzleID:  puzzleID,
+				Direction: direction,
+				Number:    number,
+				Clue:      entries[key].Clue,
+				Answer:    wordlist.Normalize(entries[key].Answer),
+			})
+		}
+	}
+	add("across", content.Across)
+	add("down", content.Down)
+	s.clues[puzzleID] = clues
+}
+
+// page returns the items on the page the filters ask for.
+func page[T any](items []T, filters Filters) []T {
+	start := min(filters.offset(), len(items))
+	end := min(start+filters.limit(), len(items))
+	return items[start:end]
+}
+
+type memoryUsers struct {
+	s *memoryStore
+}
+
+func trimUser(user *User) *User {
+	u := *user
+	u.Email = strings.Trim(u.Email, " ")
+	u.FullName = strings.Trim(u.FullName, " ")
+	u.DisplayName = strings.Trim(u.DisplayName, " ")
+	u.Bio = strings.Trim(u.Bio, " ")
+	u.AvatarURL = strings.Trim(u.AvatarURL, " ")
+	u.Password.plaintext = nil
+	return &u
+}
+
+func (m memoryUsers) Insert(ctx context.Context, user *User) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored := trimUser(user)
+	if m.s.emailTaken(stored.Email, 0) {
+		return ErrDuplicateEmail
+	}
+	m.s.nextUserID++
+	stored.ID = m.s.nextUserID
+	stored.CreatedAt = time.Now()
+	stored.Version = 1
+	m.s.users[stored.ID] = stored
+
+	user.ID, user.CreatedAt, user.Version = stored.ID, stored.CreatedAt, stored.Version
+	return nil
+}
+
+func (m memoryUsers) Update(ctx context.Context, user *User) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	current, ok := m.s.users[user.ID]
+	if !ok || current.Version != user.Version {
+		return ErrEditConflict
+	}
+	stored := trimUser(user)
+	if m.s.emailTaken(stored.Email, stored.ID) {
+		return ErrDuplicateEmail
+	}
+	stored.CreatedAt = current.CreatedAt
+	stored.Version++
+	m.s.users[stored.ID] = stored
+
+	user.Version = stored.Version
+	return nil
+}
+
+func (m memoryUsers) GetByEmail(ctx context.Context, email string) (*User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, u := range m.s.users {
+		if u.Email == email {
+			user := *u
+			return &user, nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (m memoryUsers) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	token, ok := m.s.tokens[string(hash[:])]
+	if !ok || token.Scope != tokenScope || !token.Expiry.After(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+	u, ok := m.s.users[token.UserID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	user := *u
+	return &user, nil
+}
+
+func (m memoryUsers) GetByID(ctx context.Context, id int) (*User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u, ok := m.s.users[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	user := *u
+	return &user, nil
+}
+
+func (m memoryUsers) Delete(ctx context.Context, id int, deletePuzzles bool) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	user, ok := m.s.users[id]
+	if !ok || user.Email == DeletedUserEmail {
+		return ErrRecordNotFound
+	}
+	placeholder := 0
+	for _, u := range m.s.users {
+		if u.Email == DeletedUserEmail {
+			placeholder = u.ID
+		}
+	}
+
+	for puzzleID, p := range m.s.puzzles {
+		if p.Author.ID != id {
+			continue
+		}
+		if !p.Published || deletePuzzles {
+			m.s.deletePuzzle(puzzleID)
+			continue
+		}
+		p.Author.ID = placeholder
+		p.UpdatedAt = time.Now()
+		p.Version++
+	}
+	for collectionID, c := range m.s.collections {
+		if c.OwnerID != id {
+			continue
+		}
+		if !c.Published || deletePuzzles {
+			delete(m.s.collections, collectionID)
+			delete(m.s.collectionPuzzles, collectionID)
+			continue
+		}
+		c.OwnerID = placeholder
+		c.UpdatedAt = time.Now()
+		c.Version++
+	}
+	for listID, list := range m.s.wordLists {
+		if list.OwnerID == id {
+			delete(m.s.wordLists, listID)
+			delete(m.s.words, listID)
+		}
+	}
+	for importID, imp := range m.s.imports {
+		if imp.OwnerID == id {
+			delete(m.s.imports, importID)
+		}
+	}
+
+	for _, event := range m.s.events {
+		if event.ActorID == id {
+			event.ActorID = 0
+			event.ActorEmail = ""
+			event.IP = ""
+		}
+		email, _ := event.Details["email"].(string)
+		if (event.TargetType == "user" && event.TargetID == id) || strings.EqualFold(email, user.Email) {
+			event.Details = maps.Clone(event.Details)
+			delete(event.Details, "email")
+		}
+	}
+
+	for hash, token := range m.s.tokens {
+		if token.UserID == id {
+			delete(m.s.tokens, hash)
+		}
+	}
+	for _, votes := range m.s.votes {
+		delete(votes, id)
+	}
+	delete(m.s.permissions, id)
+	delete(m.s.users, id)
+	return nil
+}
+
+var userSortValues = map[string]func(a, b *User) int{
+	"id": func(a, b *User) int {
+		return cmp.Compare(a.ID, b.ID)
+	},
+	"email": func(a, b *User) int {
+		return strings.Compare(a.Email, b.Email)
+	},
+	"display_name": func(a, b *User) int {
+		return strings.Compare(a.DisplayName, b.DisplayName)
+	},
+	"created_at": func(a, b *User) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	},
+}
+
+func (m memoryUsers) List(ctx context.Context, search UserSearch, filters Filters) ([]*User, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	query := strings.ToLower(search.Query)
+	users := []*User{}
+	for _, u := range m.s.users {
+		if u.Email == DeletedUserEmail {
+			continue
+		}
+		matches := strings.Contains(strings.ToLower(u.Email), query) ||
+			strings.Contains(strings.ToLower(u.FullName), query) ||
+			strings.Contains(strings.ToLower(u.DisplayName), query)
+		if !matches || (search.Activated != "all" && u.Activated != (search.Activated == "true")) {
+			continue
+		}
+		user := *u
+		users = append(users, &user)
+	}
+
+	compare := userSortValues[filters.sortColumn()]
+	slices.SortFunc(users, func(a, b *User) int {
+		c := compare(a, b)
+		if filters.sortDirection() == "DESC" {
+			c = -c
+		}
+		return cmp.Or(c, cmp.Compare(a.ID, b.ID))
+	})
+
+	metadata := calculateMetadata(len(users), filters.Page, filters.PageSize)
+	return page(users, filters), metadata, nil
+}
+
+type memoryTokens struct {
+	s *memoryStore
+}
+
+func (m memoryTokens) New(ctx context.Context, userID int, ttl time.Duration, scope string) (*Token, error) {
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	err = m.Insert(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (m memoryTokens) Insert(ctx context.Context, token *Token) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.users[token.UserID]; !ok {
+		return fmt.Errorf("token for user %d who does not exist", token.UserID)
+	}
+	stored := *token
+	stored.Plaintext = ""
+	m.s.tokens[string(token.Hash)] = &stored
+	return nil
+}
+
+func (m memoryTokens) DeleteAllForUser(ctx context.Context, scope string, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for hash, token := range m.s.tokens {
+		if token.Scope == scope && token.UserID == userID {
+			delete(m.s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (m memoryTokens) GetForText(ctx context.Context, plainText, scope string) (*Token, error) {
+	hash := sha256.Sum256([]byte(plainText))
+
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	t, ok := m.s.tokens[string(hash[:])]
+	if !ok || t.Scope != scope || !t.Expiry.After(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+	token := *t
+	return &token, nil
+}
+
+func (m memoryTokens) DeleteForText(ctx context.Context, plainText string) error {
+	hash := sha256.Sum256([]byte(plainText))
+
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	delete(m.s.tokens, string(hash[:]))
+	return nil
+}
+
+func (m memoryTokens) GetAllForUser(ctx context.Context, userID int) ([]Session, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	sessions := []Session{}
+	for _, token := range m.s.tokens {
+		if token.UserID == userID {
+			sessions = append(sessions, Session{Scope: token.Scope, Expiry: token.Expiry})
+		}
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return a.Expiry.Compare(b.Expiry)
+	})
+	return sessions, nil
+}
+
+type memoryPermissions struct {
+	s *memoryStore
+}
+
+func (m memoryPermissions) GetAllForUser(ctx context.Context, userID int) (Permissions, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	permissions := slices.Clone(m.s.permissions[userID])
+	slices.Sort(permissions)
+	return permissions, nil
+}
+
+func (m memoryPermissions) AddForUser(ctx context.Context, userID int, codes ...string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.users[userID]; !ok {
+		return fmt.Errorf("permissions for user %d who does not exist", userID)
+	}
+	for _, code := range codes {
+		if m.s.permissions[userID].Include(code) {
+			return fmt.Errorf("user %d already has permission %s", userID, code)
+		}
+	}
+	m.s.permissions[userID] = append(m.s.permissions[userID], codes...)
+	return nil
+}
+
+type memoryPuzzles struct {
+	s *memoryStore
+}
+
+// copyPuzzle copies a puzzle deeply enough that changing the copy's tags or
+// clues leaves the original alone.
+func copyPuzzle(p *Puzzle) *Puzzle {
+	c := *p
+	c.Tags = slices.Clone(p.Tags)
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	c.Content.Across = maps.Clone(p.Content.Across)
+	c.Content.Down = maps.Clone(p.Content.Down)
+	c.Content.Circles = slices.Clone(p.Content.Circles)
+	return &c
+}
+
+// read returns a copy of a stored puzzle as the database would return it,
+// with its author's details and difficulty votes. Puzzles whose author no
+// longer exists aren't found, as with the database's inner join.
+func (m memoryPuzzles) read(p *Puzzle) (*Puzzle, bool) {
+	u, ok := m.s.users[p.Author.ID]
+	if !ok {
+		return nil, false
+	}
+	c := copyPuzzle(p)
+	c.Author = User{ID: u.ID, FullName: u.FullName, DisplayName: u.DisplayName, Email: u.Email}
+
+	c.SolverVotes = len(m.s.votes[p.ID])
+	if c.SolverVotes > 0 {
+		total := 0
+		for _, vote := range m.s.votes[p.ID] {
+			total += vote.Rating
+		}
+		average := float64(total) / float64(c.SolverVotes)
+		c.SolverDifficulty = &average
+	}
+	return c, true
+}
+
+func (m memoryPuzzles) Insert(ctx context.Context, puzzle *Puzzle) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if puzzle.SourceHash != "" {
+		for _, p := range m.s.puzzles {
+			if p.Author.ID == puzzle.Author.ID && p.SourceHash == puzzle.SourceHash {
+				return ErrDuplicatePuzzle
+			}
+		}
+	}
+
+	now := time.Now()
+	m.s.nextPuzzleID++
+	puzzle.ID = m.s.nextPuzzleID
+	puzzle.CreatedAt = now
+	puzzle.UpdatedAt = now
+	puzzle.PublishedAt = nil
+	if puzzle.Published {
+		puzzle.PublishedAt = &now
+	}
+
+	stored := copyPuzzle(puzzle)
+	stored.Author = User{ID: puzzle.Author.ID}
+	stored.Tags = slices.Compact(slices.Sorted(slices.Values(stored.Tags)))
+	stored.SolverDifficulty = nil
+	stored.SolverVotes = 0
+	stored.Version = 1
+	m.s.puzzles[stored.ID] = stored
+	m.s.setClues(stored.ID, stored.Content)
+	return nil
+}
+
+func (m memoryPuzzles) GetByID(ctx context.Context, id int) (*Puzzle, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	p, ok := m.s.puzzles[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	puzzle, ok := m.read(p)
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return puzzle, nil
+}
+
+func (m memoryPuzzles) Update(ctx context.Context, puzzle *Puzzle) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	current, ok := m.s.puzzles[puzzle.ID]
+	if !ok || current.Version != puzzle.Version {
+		return ErrEditConflict
+	}
+
+	now := time.Now()
+	stored := copyPuzzle(puzzle)
+	stored.Author = current.Author
+	stored.CreatedAt = current.CreatedAt
+	stored.SourceHash = current.SourceHash
+	stored.Tags = slices.Compact(slices.Sorted(slices.Values(stored.Tags)))
+	stored.SolverDifficulty = nil
+	stored.SolverVotes = 0
+	stored.UpdatedAt = now
+	stored.PublishedAt = nil
+	if stored.Published {
+		stored.PublishedAt = cmp.Or(current.PublishedAt, &now)
+	}
+	stored.Version = current.Version + 1
+	m.s.puzzles[stored.ID] = stored
+	if !reflect.DeepEqual(current.Content, stored.Content) {
+		m.s.setClues(stored.ID, stored.Content)
+	}
+
+	puzzle.Version, puzzle.UpdatedAt, puzzle.PublishedAt = stored.Version, stored.UpdatedAt, stored.PublishedAt
+	return nil
+}
+
+func (m memoryPuzzles) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.deletePuzzle(id)
+	return nil
+}
+
+// matches reports whether a puzzle passes the filters of a puzzle listing.
+func (s PuzzleSearch) matches(p *Puzzle, published1, published2 bool) bool {
+	query := strings.ToLower(s.Query)
+	switch {
+	case p.Published != published1 && p.Published != published2:
+		return false
+	case query != "" && !strings.Contains(strings.ToLower(p.Title), query) && !strings.Contains(strings.ToLower(p.Description), query):
+		return false
+	case s.AuthorID != 0 && p.Author.ID != s.AuthorID:
+		return false
+	case s.Width != 0 && p.Width != s.Width, s.Height != 0 && p.Height != s.Height:
+		return false
+	case !s.CreatedAfter.IsZero() && p.CreatedAt.Before(s.CreatedAfter):
+		return false
+	case !s.CreatedBefore.IsZero() && !p.CreatedAt.Before(s.CreatedBefore):
+		return false
+	case len(s.Difficulties) > 0 && !slices.Contains(s.Difficulties, p.Difficulty):
+		return false
+	}
+	for _, tag := range s.Tags {
+		if !slices.Contains(p.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareSortValues compares two values of a puzzle sort key, which are
+// strings holding values of the key's SQL type.
+func compareSortValues(cast, a, b string) int {
+	switch cast {
+	case "timestamptz":
+		ta, _ := time.Parse(time.RFC3339Nano, a)
+		tb, _ := time.Parse(time.RFC3339Nano, b)
+		return ta.Compare(tb)
+	case "int":
+		ia, _ := strconv.Atoi(a)
+		ib, _ := strconv.Atoi(b)
+		return cmp.Compare(ia, ib)
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func (m memoryPuzzles) List(ctx context.Context, published1, published2 bool, search PuzzleSearch, fields []string, filters Filters) ([]*Puzzle, Metadata, error) {
+	key := puzzleSortKeys[filters.sortColumn()]
+	value := key.value
+	if value == nil {
+		value = puzzleSortKeys["created_at"].value
+	}
+	direction := 1
+	if filters.sortDirection() == "DESC" {
+		direction = -1
+	}
+	compare := func(aValue string, aID int, bValue string, bID int) int {
+		return direction * cmp.Or(compareSortValues(key.cast, aValue, bValue), cmp.Compare(aID, bID))
+	}
+
+	var after *cursor
+	if filters.After != "" {
+		c, err := decodeCursor(filters.After)
+		if err != nil || c.Sort != filters.Sort || key.cast == "" {
+			return nil, Metadata{}, ErrInvalidCursor
+		}
+		after = &c
+	}
+
+	m.s.mu.Lock()
+	matched := []*Puzzle{}
+	for _, p := range m.s.puzzles {
+		puzzle, ok := m.read(p)
+		if !ok || !search.matches(puzzle, published1, published2) {
+			continue
+		}
+		if after != nil && compare(value(puzzle), puzzle.ID, after.Value, after.ID) <= 0 {
+			continue
+		}
+		matched = append(matched, puzzle)
+	}
+	m.s.mu.Unlock()
+
+	slices.SortFunc(matched, func(a, b *Puzzle) int {
+		return compare(value(a), a.ID, value(b), b.ID)
+	})
+
+	var metadata Metadata
+	if after != nil {
+		metadata = Metadata{PageSize: filters.PageSize}
+		matched = matched[:min(len(matched), filters.limit())]
+	} else {
+		metadata = calculateMetadata(len(matched), filters.Page, filters.PageSize)
+		matched = page(matched, filters)
+	}
+
+	var puzzles []*Puzzle
+	for _, puzzle := range matched {
+		if !slices.Contains(fields, "description") {
+			puzzle.Description = ""
+		}
+		if !slices.Contains(fields, "content") {
+			puzzle.Content = PuzzleData{}
+		}
+		puzzles = append(puzzles, puzzle)
+	}
+
+	if key.cast != "" && len(puzzles) == filters.limit() {
+		last := puzzles[len(puzzles)-1]
+		metadata.NextCursor = encodeCursor(cursor{Sort: filters.Sort, Value: key.value(last), ID: last.ID})
+	}
+	return puzzles, metadata, nil
+}
+
+func (m memoryPuzzles) Export(ctx context.Context, filter PuzzleExportFilter, fn func(*Puzzle) error) error {
+	m.s.mu.Lock()
+	var puzzles []*Puzzle
+	for _, p := range m.s.puzzles {
+		switch {
+		case filter.AuthorID != 0 && p.Author.ID != filter.AuthorID:
+			continue
+		case !filter.CreatedAfter.IsZero() && p.CreatedAt.Before(filter.CreatedAfter):
+			continue
+		case !filter.CreatedBefore.IsZero() && !p.CreatedAt.Before(filter.CreatedBefore):
+			continue
+		}
+		puzzle, ok := m.read(p)
+		if !ok {
+			puzzle = copyPuzzle(p)
+			puzzle.Author = User{}
+		}
+		puzzle.Author.Email = ""
+		puzzles = append(puzzles, puzzle)
+	}
+	m.s.mu.Unlock()
+
+	slices.SortFunc(puzzles, func(a, b *Puzzle) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	for _, puzzle := range puzzles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn(puzzle)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m memoryPuzzles) GetIDBySourceHash(ctx context.Context, authorID int, hash string) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, p := range m.s.puzzles {
+		if p.Author.ID == authorID && p.SourceHash == hash {
+			return p.ID, nil
+		}
+	}
+	return 0, ErrRecordNotFound
+}
+
+func (m memoryPuzzles) PublishedStates(ctx context.Context, ids []int) (map[int]bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	states := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if p, ok := m.s.puzzles[id]; ok {
+			states[id] = p.Published
+		}
+	}
+	return states, nil
+}
+
+func (m memoryPuzzles) AnswerUses(ctx context.Context, answers []string, excludeID int) ([]AnswerUse, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	uses := []AnswerUse{}
+	for _, p := range m.s.puzzles {
+		if !p.Published || p.ID == excludeID {
+			continue
+		}
+		used := map[string]bool{}
+		for _, clues := range []map[string]ClueData{p.Content.Across, p.Content.Down} {
+			for number, clue := range clues {
+				answer := wordlist.Normalize(clue.Answer)
+				if _, err := strconv.Atoi(number); err != nil || used[answer] || !slices.Contains(answers, answer) {
+					continue
+				}
+				used[answer] = true
+				uses = append(uses, AnswerUse{Answer: answer, PuzzleID: p.ID, Title: p.Title, PublishedAt: p.PublishedAt})
+			}
+		}
+	}
+
+	slices.SortFunc(uses, func(a, b AnswerUse) int {
+		if c := strings.Compare(a.Answer, b.Answer); c != 0 {
+			return c
+		}
+		switch {
+		case a.PublishedAt == nil && b.PublishedAt != nil:
+			return 1
+		case a.PublishedAt != nil && b.PublishedAt == nil:
+			return -1
+		case a.PublishedAt != nil && b.PublishedAt != nil:
+			if c := b.PublishedAt.Compare(*a.PublishedAt); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(b.PuzzleID, a.PuzzleID)
+	})
+	return uses, nil
+}
+
+func (m memoryPuzzles) SetDifficultyVote(ctx context.Context, puzzleID, userID, rating int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.puzzles[puzzleID]; !ok {
+		return fmt.Errorf("vote for puzzle %d which does not exist", puzzleID)
+	}
+	if _, ok := m.s.users[userID]; !ok {
+		return fmt.Errorf("vote by user %d who does not exist", userID)
+	}
+	if m.s.votes[puzzleID] == nil {
+		m.s.votes[puzzleID] = map[int]DifficultyVote{}
+	}
+	m.s.votes[puzzleID][userID] = DifficultyVote{PuzzleID: puzzleID, Rating: rating, CreatedAt: time.Now()}
+	return nil
+}
+
+func (m memoryPuzzles) GetDifficultyVotesForUser(ctx context.Context, userID int) ([]DifficultyVote, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	votes := []DifficultyVote{}
+	for _, byUser := range m.s.votes {
+		if vote, ok := byUser[userID]; ok {
+			votes = append(votes, vote)
+		}
+	}
+	slices.SortFunc(votes, func(a, b DifficultyVote) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return votes, nil
+}
+
+type memoryAuthors struct {
+	s *memoryStore
+}
+
+func (m memoryAuthors) GetByID(ctx context.Context, id int) (*Author, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	u, ok := m.s.users[id]
+	if !ok || !u.Activated {
+		return nil, ErrRecordNotFound
+	}
+	author := &Author{
+		ID:          u.ID,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarURL,
+		JoinedAt:    u.CreatedAt,
+	}
+	for _, p := range m.s.puzzles {
+		if p.Author.ID == id && p.Published {
+			author.PublishedPuzzles++
+		}
+	}
+	return author, nil
+}
+
+type memoryTags struct {
+	s *memoryStore
+}
+
+func (m memoryTags) GetAll(ctx context.Context) ([]*Tag, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	counts := map[string]int{}
+	for _, p := range m.s.puzzles {
+		if !p.Published {
+			continue
+		}
+		for _, tag := range p.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := []*Tag{}
+	for name, count := range counts {
+		tags = append(tags, &Tag{Name: name, Puzzles: count})
+	}
+	slices.SortFunc(tags, func(a, b *Tag) int {
+		return cmp.Or(cmp.Compare(b.Puzzles, a.Puzzles), strings.Compare(a.Name, b.Name))
+	})
+	return tags, nil
+}
+
+type memoryCollections struct {
+	s *memoryStore
+}
+
+// read returns a copy of a stored collection with its puzzle count.
+func (m memoryCollections) read(c *Collection) *Collection {
+	collection := *c
+	collection.PuzzleCount = len(m.s.collectionPuzzles[c.ID])
+	return &collection
+}
+
+func (m memoryCollections) Insert(ctx context.Context, collection *Collection, puzzleIDs []int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.users[collection.OwnerID]; !ok {
+		return fmt.Errorf("collection for user %d who does not exist", collection.OwnerID)
+	}
+	for _, id := range puzzleIDs {
+		if _, ok := m.s.puzzles[id]; !ok {
+			return fmt.Errorf("collection puzzle %d which does not exist", id)
+		}
+	}
+	now := time.Now()
+	m.s.nextCollectionID++
+	collection.ID = m.s.nextCollectionID
+	collection.CreatedAt = now
+	collection.UpdatedAt = now
+	collection.Version = 1
+
+	collection.PuzzleCount = len(puzzleIDs)
+
+	stored := *collection
+	stored.PuzzleCount = 0
+	m.s.collections[stored.ID] = &stored
+	m.s.collectionPuzzles[stored.ID] = slices.Clone(puzzleIDs)
+	return nil
+}
+
+func (m memoryCollections) GetByID(ctx context.Context, id int) (*Collection, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	c, ok := m.s.collections[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return m.read(c), nil
+}
+
+func (m memoryCollections) Update(ctx context.Context, collection *Collection) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	current, ok := m.s.collections[collection.ID]
+	if !ok || current.Version != collection.Version {
+		return ErrEditConflict
+	}
+	stored := *collection
+	stored.OwnerID = current.OwnerID
+	stored.CreatedAt = current.CreatedAt
+	stored.UpdatedAt = time.Now()
+	stored.PuzzleCount = 0
+	stored.Version = current.Version + 1
+	m.s.collections[stored.ID] = &stored
+
+	collection.Version, collection.UpdatedAt = stored.Version, stored.UpdatedAt
+	return nil
+}
+
+func (m memoryCollections) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.collections[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.collections, id)
+	delete(m.s.collectionPuzzles, id)
+	return nil
+}
+
+var collectionSortValues = map[string]func(a, b *Collection) int{
+	"id": func(a, b *Collection) int {
+		return cmp.Compare(a.ID, b.ID)
+	},
+	"title": func(a, b *Collection) int {
+		return strings.Compare(a.Title, b.Title)
+	},
+	"created_at": func(a, b *Collection) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	},
+	"updated_at": func(a, b *Collection) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	},
+}
+
+func (m memoryCollections) List(ctx context.Context, published1, published2 bool, filters Filters) ([]*Collection, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	collections := []*Collection{}
+	for _, c := range m.s.collections {
+		if c.Published == published1 || c.Published == published2 {
+			collections = append(collections, m.read(c))
+		}
+	}
+
+	compare := collectionSortValues[filters.sortColumn()]
+	slices.SortFunc(collections, func(a, b *Collection) int {
+		c := compare(a, b)
+		if filters.sortDirection() == "DESC" {
+			c = -c
+		}
+		return cmp.Or(c, cmp.Compare(a.ID, b.ID))
+	})
+
+	metadata := calculateMetadata(len(collections), filters.Page, filters.PageSize)
+	return page(collections, filters), metadata, nil
+}
+
+func (m memoryCollections) GetAllForOwner(ctx context.Context, ownerID int) ([]*Collection, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	collections := []*Collection{}
+	for _, c := range m.s.collections {
+		if c.OwnerID == ownerID {
+			collections = append(collections, m.read(c))
+		}
+	}
+	slices.SortFunc(collections, func(a, b *Collection) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return collections, nil
+}
+
+func (m memoryCollections) GetPuzzles(ctx context.Context, collectionID int, publishedOnly bool) ([]*Puzzle, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	puzzles := []*Puzzle{}
+	for _, id := range m.s.collectionPuzzles[collectionID] {
+		puzzle, ok := memoryPuzzles{m.s}.read(m.s.puzzles[id])
+		if !ok || (publishedOnly && !puzzle.Published) {
+			continue
+		}
+		// the database leaves out the description and content
+		puzzle.Description = ""
+		puzzle.Content = PuzzleData{}
+		puzzles = append(puzzles, puzzle)
+	}
+	return puzzles, nil
+}
+
+func (m memoryCollections) SetPuzzles(ctx context.Context, collectionID int, puzzleIDs []int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	c, ok := m.s.collections[collectionID]
+	if !ok {
+		return fmt.Errorf("puzzles for collection %d which does not exist", collectionID)
+	}
+	for _, id := range puzzleIDs {
+		if _, ok := m.s.puzzles[id]; !ok {
+			return fmt.Errorf("collection puzzle %d which does not exist", id)
+		}
+	}
+	m.s.collectionPuzzles[collectionID] = slices.Clone(puzzleIDs)
+	c.UpdatedAt = time.Now()
+	c.Version++
+	return nil
+}
+
+func (m memoryCollections) UnpublishedPuzzleIDs(ctx context.Context, collectionID int) ([]int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	ids := []int{}
+	for _, id := range m.s.collectionPuzzles[collectionID] {
+		if !m.s.puzzles[id].Published {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type memoryWordLists struct {
+	s *memoryStore
+}
+
+// read returns a copy of a stored word list with its word count.
+func (m memoryWordLists) read(l *WordList) *WordList {
+	list := *l
+	list.WordCount = len(m.s.words[l.ID])
+	return &list
+}
+
+func (m memoryWordLists) Insert(ctx context.Context, list *WordList) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.users[list.OwnerID]; !ok {
+		return fmt.Errorf("word list for user %d who does not exist", list.OwnerID)
+	}
+	now := time.Now()
+	m.s.nextListID++
+	list.ID = m.s.nextListID
+	list.CreatedAt = now
+	list.UpdatedAt = now
+
+	stored := *list
+	stored.Shared = false
+	stored.WordCount = 0
+	m.s.wordLists[stored.ID] = &stored
+	return nil
+}
+
+func (m memoryWordLists) GetByID(ctx context.Context, id int) (*WordList, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	l, ok := m.s.wordLists[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return m.read(l), nil
+}
+
+// available returns the house list and the user's own lists, or only the
+// list with listID when it's set, with the house list first and the user's
+// lists in the order they were created.
+func (m memoryWordLists) available(userID, listID int) []*WordList {
+	var lists []*WordList
+	for _, l := range m.s.wordLists {
+		if (l.Shared || l.OwnerID == userID) && (listID == 0 || l.ID == listID) {
+			lists = append(lists, l)
+		}
+	}
+	slices.SortFunc(lists, func(a, b *WordList) int {
+		return cmp.Or(cmp.Compare(a.OwnerID, b.OwnerID), cmp.Compare(a.ID, b.ID))
+	})
+	return lists
+}
+
+func (m memoryWordLists) GetAllForUser(ctx context.Context, userID int) ([]*WordList, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	lists := []*WordList{}
+	for _, l := range m.available(userID, 0) {
+		lists = append(lists, m.read(l))
+	}
+	slices.SortStableFunc(lists, func(a, b *WordList) int {
+		return cmp.Or(cmp.Compare(a.OwnerID, b.OwnerID), strings.Compare(a.Name, b.Name))
+	})
+	return lists, nil
+}
+
+func (m memoryWordLists) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.wordLists[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.wordLists, id)
+	delete(m.s.words, id)
+	return nil
+}
+
+func (m memoryWordLists) AddWords(ctx context.Context, listID int, entries []wordlist.Entry, replace bool) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	l, ok := m.s.wordLists[listID]
+	if !ok {
+		return fmt.Errorf("words for list %d which does not exist", listID)
+	}
+	if replace || m.s.words[listID] == nil {
+		m.s.words[listID] = map[string]wordlist.Entry{}
+	}
+	for _, entry := range entries {
+		m.s.words[listID][entry.Word] = entry
+	}
+	l.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m memoryWordLists) SetWord(ctx context.Context, listID int, entry wordlist.Entry) error {
+	return m.AddWords(ctx, listID, []wordlist.Entry{entry}, false)
+}
+
+func (m memoryWordLists) DeleteWord(ctx context.Context, listID int, word string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.words[listID][word]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.words[listID], word)
+	return nil
+}
+
+// merged returns the words passing keep from the lists a user can use, with
+// the user's own lists overriding the house list and newer lists overriding
+// older ones.
+func (m memoryWordLists) merged(userID, listID int, keep func(wordlist.Entry) bool) []*Word {
+	byWord := map[string]*Word{}
+	for _, l := range m.available(userID, listID) {
+		for word, entry := range m.s.words[l.ID] {
+			byWord[word] = &Word{Entry: entry, ListID: l.ID}
+		}
+	}
+
+	words := []*Word{}
+	for _, word := range byWord {
+		if keep(word.Entry) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func (m memoryWordLists) Lookup(ctx context.Context, userID int, lookup WordLookup, filters Filters) ([]*Word, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	words := m.merged(userID, lookup.ListID, func(entry wordlist.Entry) bool {
+		return wordlist.Match(lookup.Pattern, entry.Word) && entry.Score >= lookup.MinScore
+	})
+	slices.SortFunc(words, func(a, b *Word) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Word, b.Word))
+	})
+
+	metadata := calculateMetadata(len(words), filters.Page, filters.PageSize)
+	return page(words, filters), metadata, nil
+}
+
+func (m memoryWordLists) GetForFill(ctx context.Context, userID, listID, minScore int, lengths []int) ([]wordlist.Entry, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	words := m.merged(userID, listID, func(entry wordlist.Entry) bool {
+		return slices.Contains(lengths, len(entry.Word)) && entry.Score >= minScore
+	})
+	var entries []wordlist.Entry
+	for _, word := range words {
+		entries = append(entries, wordlist.Entry{Word: word.Word, Score: word.Score})
+	}
+	slices.SortFunc(entries, func(a, b wordlist.Entry) int {
+		return strings.Compare(a.Word, b.Word)
+	})
+	return entries, nil
+}
+
+func (m memoryWordLists) GetEntries(ctx context.Context, listID int) ([]wordlist.Entry, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	entries := slices.Collect(maps.Values(m.s.words[listID]))
+	slices.SortFunc(entries, func(a, b wordlist.Entry) int {
+		return strings.Compare(a.Word, b.Word)
+	})
+	return nonNil(entries), nil
+}
+
+type memoryClues struct {
+	s *memoryStore
+}
+
+func (m memoryClues) GetByAnswer(ctx context.Context, answer string, publishedOnly bool, filters Filters) ([]*ClueUse, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	uses := []*ClueUse{}
+	for puzzleID, clues := range m.s.clues {
+		p := m.s.puzzles[puzzleID]
+		if publishedOnly && !p.Published {
+			continue
+		}
+		for _, clue := range clues {
+			if clue.Answer != answer {
+				continue
+			}
+			use := *clue
+			use.PuzzleTitle = p.Title
+			use.Published = p.Published
+			use.PublishedAt = p.PublishedAt
+			use.CreatedAt = p.CreatedAt
+			uses = append(uses, &use)
+		}
+	}
+
+	compare := func(a, b *ClueUse) int {
+		return cmp.Or(a.PublishedAt, &a.CreatedAt).Compare(*cmp.Or(b.PublishedAt, &b.CreatedAt))
+	}
+	if filters.sortColumn() == "title" {
+		compare = func(a, b *ClueUse) int {
+			return strings.Compare(a.PuzzleTitle, b.PuzzleTitle)
+		}
+	}
+	slices.SortFunc(uses, func(a, b *ClueUse) int {
+		c := compare(a, b)
+		if filters.sortDirection() == "DESC" {
+			c = -c
+		}
+		return cmp.Or(c, cmp.Compare(b.ID, a.ID))
+	})
+
+	metadata := calculateMetadata(len(uses), filters.Page, filters.PageSize)
+	return page(uses, filters), metadata, nil
+}
+
+type memoryImports struct {
+	s *memoryStore
+}
+
+func copyImport(imp *Import) *Import {
+	c := *imp
+	c.Results = nonNil(slices.Clone(imp.Results))
+	return &c
+}
+
+func (m memoryImports) Insert(ctx context.Context, imp *Import) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.users[imp.OwnerID]; !ok {
+		return fmt.Errorf("import for user %d who does not exist", imp.OwnerID)
+	}
+	m.s.nextImportID++
+	imp.ID = m.s.nextImportID
+	imp.Status = ImportPending
+	imp.CreatedAt = time.Now()
+	imp.Results = nonNil(imp.Results)
+
+	stored := copyImport(imp)
+	stored.Processed = 0
+	stored.Results = []ImportResult{}
+	stored.Error = ""
+	stored.FinishedAt = nil
+	m.s.imports[stored.ID] = stored
+	return nil
+}
+
+func (m memoryImports) GetByID(ctx context.Context, id int) (*Import, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	imp, ok := m.s.imports[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return copyImport(imp), nil
+}
+
+func (m memoryImports) GetAllForOwner(ctx context.Context, ownerID int) ([]*Import, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	imports := []*Import{}
+	for _, imp := range m.s.imports {
+		if imp.OwnerID == ownerID {
+			imports = append(imports, copyImport(imp))
+		}
+	}
+	slices.SortFunc(imports, func(a, b *Import) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return imports, nil
+}
+
+func (m memoryImports) GetByChecksum(ctx context.Context, ownerID int, checksum string) (*Import, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	staleBefore := time.Now().Add(-ImportStaleAfter)
+	var latest *Import
+	for _, imp := range m.s.imports {
+		switch {
+		case imp.OwnerID != ownerID || imp.Checksum != checksum || imp.DryRun:
+			continue
+		case imp.Status == ImportFailed:
+			continue
+		case imp.Status != ImportDone && !imp.CreatedAt.After(staleBefore):
+			continue
+		}
+		if latest == nil || imp.ID > latest.ID {
+			latest = imp
+		}
+	}
+	if latest == nil {
+		return nil, ErrRecordNotFound
+	}
+	return copyImport(latest), nil
+}
+
+func (m memoryImports) Update(ctx context.Context, imp *Import) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.imports[imp.ID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	stored.Status = imp.Status
+	stored.Processed = imp.Processed
+	stored.Results = nonNil(slices.Clone(imp.Results))
+	stored.Error = imp.Error
+	stored.FinishedAt = nil
+	if imp.Status == ImportDone || imp.Status == ImportFailed {
+		now := time.Now()
+		stored.FinishedAt = &now
+	}
+
+	imp.FinishedAt = stored.FinishedAt
+	return nil
+}
+
+type memoryAudit struct {
+	s *memoryStore
+}
+
+func (m memoryAudit) Insert(ctx context.Context, event *AuditEvent) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if event.Details == nil {
+		event.Details = map[string]any{}
+	}
+	m.s.nextEventID++
+	event.ID = m.s.nextEventID
+	event.CreatedAt = time.Now()
+
+	stored := *event
+	if _, ok := m.s.users[stored.ActorID]; !ok {
+		stored.ActorID = 0
+	}
+	m.s.events = append(m.s.events, &stored)
+	return nil
+}
+
+func (m memoryAudit) List(ctx context.Context, filter AuditFilter, filters Filters) ([]*AuditEvent, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	events := []*AuditEvent{}
+	for _, e := range m.s.events {
+		switch {
+		case filter.ActorID != 0 && e.ActorID != filter.ActorID:
+			continue
+		case filter.Action != "" && e.Action != filter.Action:
+			continue
+		case filter.TargetType != "" && e.TargetType != filter.TargetType:
+			continue
+		case filter.TargetID != 0 && e.TargetID != filter.TargetID:
+			continue
+		case !filter.CreatedAfter.IsZero() && e.CreatedAt.Before(filter.CreatedAfter):
+			continue
+		case !filter.CreatedBefore.IsZero() && !e.CreatedAt.Before(filter.CreatedBefore):
+			continue
+		}
+		event := *e
+		events = append(events, &event)
+	}
+
+	slices.SortFunc(events, func(a, b *AuditEvent) int {
+		c := cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+		if filters.sortDirection() == "DESC" {
+			return -c
+		}
+		return c
+	})
+
+	metadata := calculateMetadata(len(events), filters.Page, filters.PageSize)
+	return page(events, filters), metadata, nil
+}
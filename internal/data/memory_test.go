@@ -0,0 +1,271 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ggetzie/badwords_be/internal/wordlist"
+)
+
+func newMemoryUser(t *testing.T, m Models, email string) *User {
+	t.Helper()
+
+	user := &User{Email: email, FullName: "Test User", DisplayName: email, Activated: true}
+	user.Password.hash = []byte("not a real hash")
+	err := m.Users.Insert(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestMemoryUsersDuplicateEmail(t *testing.T) {
+	m := NewMemoryModels()
+	ctx := context.Background()
+	newMemoryUser(t, m, "alice@example.com")
+	bob := newMemoryUser(t, m, "bob@example.com")
+
+	err := m.Users.Insert(ctx, &User{Email: "alice@example.com"})
+	if !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("inserting a duplicate email got %v; want %v", err, ErrDuplicateEmail)
+	}
+
+	bob.Email = "alice@example.com"
+	err = m.Users.Update(ctx, bob)
+	if !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("updating to a duplicate email got %v; want %v", err, ErrDuplicateEmail)
+	}
+}
+
+func TestMemoryUsersEditConflict(t *testing.T) {
+	m := NewMemoryModels()
+	ctx := context.Background()
+	user := newMemoryUser(t, m, "alice@example.com")
+
+	first, err := m.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.FullName = "Alice"
+	err = m.Users.Update(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.FullName = "Alicia"
+	err = m.Users.Update(ctx, second)
+	if !errors.Is(err, ErrEditConflict) {
+		t.Errorf("updating a stale copy got %v; want %v", err, ErrEditConflict)
+	}
+}
+
+func TestMemoryUsersConcurrentUpdates(t *testing.T) {
+	m := NewMemoryModels()
+	ctx := context.Background()
+	user := newMemoryUser(t, m, "alice@example.com")
+
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := m.Users.GetByID(ctx, user.ID)
+			if err != nil {
+				results <- err
+				return
+			}
+			u.FullName = "Alice"
+			results <- m.Users.Update(ctx, u)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		if err != nil && !errors.Is(err, ErrEditConflict) {
+			t.Errorf("got unexpected error %v", err)
+		}
+	}
+}
+
+func TestMemoryTokenExpiry(t *testing.T) {
+	m := NewMemoryModels()
+	ctx := context.Background()
+	user := newMemoryUser(t, m, "alice@example.com")
+
+	valid, err := m.Tokens.New(ctx, user.ID, time.Hour, ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := m.Tokens.New(ctx, user.ID, -time.Minute, ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.Users.GetForToken(ctx, ScopeAuthentication, valid.Plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("got user %d for the token; want %d", got.ID, user.ID)
+	}
+
+	_, err = m.Users.GetForToken(ctx, ScopeAuthentication, expired.Plaintext)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expired token got %v; want %v", err, ErrRecordNotFound)
+	}
+	_, err = m.Users.GetForToken(ctx, "other-scope", valid.Plaintext)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("token in the wrong scope got %v; want %v", err, ErrRecordNotFound)
+	}
+}
+
+func TestMemoryPuzzlesCopy(t *testing.T) {
+	m := NewMemoryModels()
+	ctx := context.Background()
+	user := newMemoryUser(t, m, "alice@example.com")
+
+	puzzle := &Puzzle{Title: "Cats", Width: 3, Height: 3, Author: *user, Tags: []string{"animals"}}
+	err := m.Puzzles.Insert(ctx, puzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// changing the caller's copy mustn't change the stored puzzle
+	puzzle.Tags[0] = "changed"
+	got, err := m.Puzzles.GetByID(ctx, puzzle.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Tags[0] != "animals" {
+		t.Errorf("got tag %q; want %q", got.Tags[0], "animals")
+	}
+}
+
+func TestMemoryWordListsOverride(t *testing.T) {
+	m := NewMemoryModels()
+	ctx := context.Background()
+	user := newMemoryUser(t, m, "alice@example.com")
+
+	lists, err := m.WordLists.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 || !lists[0].Shared {
+		t.Fatalf("got lists %+v; want only the house list", lists)
+	}
+	house := lists[0]
+	err = m.WordLists.AddWords(ctx, house.ID, []wordlist.Entry{{Word: "CAT", Score: 50}, {Word: "COT", Score: 40}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	own := &WordList{Name: "Mine", OwnerID: user.ID}
+	err = m.WordLists.Insert(ctx, own)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.WordLists.SetWord(ctx, own.ID, wordlist.Entry{Word: "COT", Score: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters := Filters{Page: 1, PageSize: 10, Sort: "-score", SortSafeList: []string{"-score"}}
+	words, _, err := m.WordLists.Lookup(ctx, user.ID, WordLookup{Pattern: "C?T"}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Word{
+		{Entry: wordlist.Entry{Word: "COT", Score: 90}, ListID: own.ID},
+		{Entry: wordlist.Entry{Word: "CAT", Score: 50}, ListID: house.ID},
+	}
+	if len(words) != len(want) {
+		t.Fatalf("got %d words; want %d", len(words), len(want))
+	}
+	for i := range want {
+		if *words[i] != want[i] {
+			t.Errorf("word %d: got %+v; want %+v", i, *words[i], want[i])
+		}
+	}
+
+	// other users only see the house list
+	other := newMemoryUser(t, m, "bob@example.com")
+	entries, err := m.WordLists.GetForFill(ctx, other.ID, 0, 0, []int{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1] != (wordlist.Entry{Word: "COT", Score: 40}) {
+		t.Errorf("got entries %+v; want the house list's", entries)
+	}
+}
+
+func TestMemoryUsersDeleteCascades(t *testing.T) {
+	m := NewMemoryModels()
+	ctx := context.Background()
+	user := newMemoryUser(t, m, "alice@example.com")
+
+	puzzle := &Puzzle{Title: "Cats", Width: 3, Height: 3, Author: *user, Published: true}
+	draft := &Puzzle{Title: "Dogs", Width: 3, Height: 3, Author: *user}
+	for _, p := range []*Puzzle{puzzle, draft} {
+		err := m.Puzzles.Insert(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	shared := &Collection{Title: "Shared", OwnerID: user.ID, Published: true}
+	private := &Collection{Title: "Private", OwnerID: user.ID}
+	for _, c := range []*Collection{shared, private} {
+		err := m.Collections.Insert(ctx, c, []int{puzzle.ID, draft.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := &WordList{Name: "Mine", OwnerID: user.ID}
+	err := m.WordLists.Insert(ctx, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Imports.Insert(ctx, &Import{OwnerID: user.ID, Checksum: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Users.Delete(ctx, user.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.Collections.GetByID(ctx, shared.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OwnerID == user.ID || got.OwnerID == 0 {
+		t.Errorf("published collection has owner %d; want the placeholder", got.OwnerID)
+	}
+	if got.PuzzleCount != 1 {
+		t.Errorf("published collection has %d puzzles; want the published one only", got.PuzzleCount)
+	}
+	_, err = m.Collections.GetByID(ctx, private.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("getting the unpublished collection got %v; want %v", err, ErrRecordNotFound)
+	}
+	_, err = m.WordLists.GetByID(ctx, list.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("getting the word list got %v; want %v", err, ErrRecordNotFound)
+	}
+	imports, err := m.Imports.GetAllForOwner(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imports) != 0 {
+		t.Errorf("got %d imports; want none", len(imports))
+	}
+}
@@ -0,0 +1,257 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/ggetzie/badwords_be/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are tracked in the schema_migrations table the same way as the
+// migrate tool does, so databases it has migrated carry on from where they are.
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+var ErrDirtyMigration = errors.New("the last migration failed partway through, fix the database and force its version")
+
+var migrationFileRX = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var allMigrations = mustLoadMigrations(migrations.Files)
+
+// SchemaVersion is the version of the latest embedded migration, which the
+// code expects the database to be at.
+var SchemaVersion = allMigrations[len(allMigrations)-1].Version
+
+// Migrations returns the embedded migrations, oldest first.
+func Migrations() []Migration {
+	return slices.Clone(allMigrations)
+}
+
+func mustLoadMigrations(files fs.FS) []Migration {
+	paths, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		panic(err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, path := range paths {
+		match := migrationFileRX.FindStringSubmatch(path)
+		if match == nil {
+			panic(fmt.Sprintf("migration %s isn't named VERSION_NAME.(up|down).sql", path))
+		}
+		version, _ := strconv.Atoi(match[1])
+		sql, err := fs.ReadFile(files, path)
+		if err != nil {
+			panic(err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(sql)
+		} else {
+			m.Down = string(sql)
+		}
+	}
+
+	var all []Migration
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			panic(fmt.Sprintf("migration %d is missing its up or down file", m.Version))
+		}
+		all = append(all, *m)
+	}
+	if len(all) == 0 {
+		panic("no migrations embedded")
+	}
+	slices.SortFunc(all, func(a, b Migration) int { return a.Version - b.Version })
+	return all
+}
+
+// MigrationVersion returns the database's migration version and whether the
+// last migration failed partway through. A database that has never been
+// migrated is at version 0.
+func MigrationVersion(ctx context.Context, db *pgxpool.Pool) (int, bool, error) {
+	var version int
+	var dirty bool
+	err := db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return 0, false, nil
+	case errors.As(err, &pgErr) && pgErr.Code == "42P01": // undefined_table
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// MigrateUp applies the migrations newer than the database's version and
+// returns the ones it applied.
+func MigrateUp(ctx context.Context, db *pgxpool.Pool) ([]Migration, error) {
+	var applied []Migration
+	err := withMigrationLock(ctx, db, func(conn *pgxpool.Conn, version int) error {
+		for _, m := range allMigrations {
+			if m.Version <= version {
+				continue
+			}
+			err := runMigration(ctx, conn, m.Version, m.Up, m.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts up to steps migrations, newest first, and returns the
+// ones it reverted.
+func MigrateDown(ctx context.Context, db *pgxpool.Pool, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := withMigrationLock(ctx, db, func(conn *pgxpool.Conn, version int) error {
+		for range steps {
+			if version == 0 {
+				return nil
+			}
+			i := slices.IndexFunc(allMigrations, func(m Migration) bool { return m.Version == version })
+			if i < 0 {
+				return fmt.Errorf("no migration found for version %d", version)
+			}
+			m := allMigrations[i]
+			previous := 0
+			if i > 0 {
+				previous = allMigrations[i-1].Version
+			}
+
+			err := runMigration(ctx, conn, m.Version, m.Down, previous)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+			version = previous
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// ForceMigrationVersion records the database as being at version without
+// running any migrations, clearing the dirty flag left by a failed one.
+// Version 0 records it as never migrated.
+func ForceMigrationVersion(ctx context.Context, db *pgxpool.Pool, version int) error {
+	if version < 0 {
+		return fmt.Errorf("invalid version %d", version)
+	}
+	conn, err := lockMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer unlockMigrations(conn)
+	return setMigrationVersion(ctx, conn, version, false)
+}
+
+// migrationLockID keeps concurrent migrations, e.g. from two instances
+// starting at once, from running over each other.
+const migrationLockID = 0x62616477 // "badw"
+
+func lockMigrations(ctx context.Context, db *pgxpool.Pool) (*pgxpool.Conn, error) {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	if err != nil {
+		unlockMigrations(conn)
+		return nil, err
+	}
+	return conn, nil
+}
+
+func unlockMigrations(conn *pgxpool.Conn) {
+	// the lock has to be released even if the migration was cancelled
+	_, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	if err != nil {
+		// a connection still holding the lock mustn't go back in the pool
+		conn.Hijack().Close(context.Background())
+		return
+	}
+	conn.Release()
+}
+
+// withMigrationLock runs fn with the lock held and the database's current
+// version, refusing to if the last migration failed.
+func withMigrationLock(ctx context.Context, db *pgxpool.Pool, fn func(conn *pgxpool.Conn, version int) error) error {
+	conn, err := lockMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer unlockMigrations(conn)
+
+	var version int
+	var dirty bool
+	err = conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("version %d: %w", version, ErrDirtyMigration)
+	}
+	return fn(conn, version)
+}
+
+// runMigration runs a migration's SQL, marking the database dirty at version
+// while it runs and recording newVersion when it succeeds.
+func runMigration(ctx context.Context, conn *pgxpool.Conn, version int, sql string, newVersion int) error {
+	err := setMigrationVersion(ctx, conn, version, true)
+	if err != nil {
+		return err
+	}
+	// without arguments the whole file is sent as one multi-statement query
+	_, err = conn.Exec(ctx, sql)
+	if err != nil {
+		return err
+	}
+	return setMigrationVersion(ctx, conn, newVersion, false)
+}
+
+func setMigrationVersion(ctx context.Context, conn *pgxpool.Conn, version int, dirty bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	if version > 0 {
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
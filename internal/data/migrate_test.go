@@ -0,0 +1,57 @@
+package data
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(sql string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(sql)}
+	}
+
+	migrations := mustLoadMigrations(fstest.MapFS{
+		"000010_second.up.sql":   file("up 10"),
+		"000010_second.down.sql": file("down 10"),
+		"000002_first.up.sql":    file("up 2"),
+		"000002_first.down.sql":  file("down 2"),
+	})
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations; want 2", len(migrations))
+	}
+	want := Migration{Version: 10, Name: "second", Up: "up 10", Down: "down 10"}
+	if migrations[1] != want {
+		t.Errorf("got %+v; want %+v", migrations[1], want)
+	}
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"Missing down", fstest.MapFS{"000001_first.up.sql": file("up")}},
+		{"Bad name", fstest.MapFS{"first.up.sql": file("up"), "first.down.sql": file("down")}},
+		{"None", fstest.MapFS{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("loading the migrations didn't panic")
+				}
+			}()
+			mustLoadMigrations(tt.files)
+		})
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	all := Migrations()
+	for i := 1; i < len(all); i++ {
+		if all[i].Version <= all[i-1].Version {
+			t.Fatalf("migration %d comes after %d", all[i].Version, all[i-1].Version)
+		}
+	}
+	if SchemaVersion != all[len(all)-1].Version {
+		t.Errorf("got schema version %d; want the latest migration, %d", SchemaVersion, all[len(all)-1].Version)
+	}
+}
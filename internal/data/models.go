@@ -1,8 +1,11 @@
 package data
 
 import (
+	"context"
 	"errors"
+	"time"
 
+	"github.com/ggetzie/badwords_be/internal/wordlist"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -11,18 +14,126 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// The repositories below are implemented by the PostgreSQL models and by the
+// in-memory store from NewMemoryModels.
+
+type UserRepository interface {
+	Insert(ctx context.Context, user *User) error
+	Update(ctx context.Context, user *User) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+	GetByID(ctx context.Context, id int) (*User, error)
+	Delete(ctx context.Context, id int, deletePuzzles bool) error
+	List(ctx context.Context, search UserSearch, filters Filters) ([]*User, Metadata, error)
+}
+
+type TokenRepository interface {
+	New(ctx context.Context, userID int, ttl time.Duration, scope string) (*Token, error)
+	Insert(ctx context.Context, token *Token) error
+	DeleteAllForUser(ctx context.Context, scope string, userID int) error
+	GetForText(ctx context.Context, plainText, scope string) (*Token, error)
+	DeleteForText(ctx context.Context, plainText string) error
+	GetAllForUser(ctx context.Context, userID int) ([]Session, error)
+}
+
+type PermissionRepository interface {
+	GetAllForUser(ctx context.Context, userID int) (Permissions, error)
+	AddForUser(ctx context.Context, userID int, codes ...string) error
+}
+
+type PuzzleRepository interface {
+	Insert(ctx context.Context, puzzle *Puzzle) error
+	GetByID(ctx context.Context, id int) (*Puzzle, error)
+	Update(ctx context.Context, puzzle *Puzzle) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, published1, published2 bool, search PuzzleSearch, fields []string, filters Filters) ([]*Puzzle, Metadata, error)
+	Export(ctx context.Context, filter PuzzleExportFilter, fn func(*Puzzle) error) error
+	GetIDBySourceHash(ctx context.Context, authorID int, hash string) (int, error)
+	PublishedStates(ctx context.Context, ids []int) (map[int]bool, error)
+	AnswerUses(ctx context.Context, answers []string, excludeID int) ([]AnswerUse, error)
+	SetDifficultyVote(ctx context.Context, puzzleID, userID, rating int) error
+	GetDifficultyVotesForUser(ctx context.Context, userID int) ([]DifficultyVote, error)
+}
+
+type AuthorRepository interface {
+	GetByID(ctx context.Context, id int) (*Author, error)
+}
+
+type TagRepository interface {
+	GetAll(ctx context.Context) ([]*Tag, error)
+}
+
+type CollectionRepository interface {
+	Insert(ctx context.Context, collection *Collection, puzzleIDs []int) error
+	GetByID(ctx context.Context, id int) (*Collection, error)
+	Update(ctx context.Context, collection *Collection) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, published1, published2 bool, filters Filters) ([]*Collection, Metadata, error)
+	GetAllForOwner(ctx context.Context, ownerID int) ([]*Collection, error)
+	GetPuzzles(ctx context.Context, collectionID int, publishedOnly bool) ([]*Puzzle, error)
+	SetPuzzles(ctx context.Context, collectionID int, puzzleIDs []int) error
+	UnpublishedPuzzleIDs(ctx context.Context, collectionID int) ([]int, error)
+}
+
+type WordListRepository interface {
+	Insert(ctx context.Context, list *WordList) error
+	GetByID(ctx context.Context, id int) (*WordList, error)
+	GetAllForUser(ctx context.Context, userID int) ([]*WordList, error)
+	Delete(ctx context.Context, id int) error
+	AddWords(ctx context.Context, listID int, entries []wordlist.Entry, replace bool) error
+	SetWord(ctx context.Context, listID int, entry wordlist.Entry) error
+	DeleteWord(ctx context.Context, listID int, word string) error
+	Lookup(ctx context.Context, userID int, lookup WordLookup, filters Filters) ([]*Word, Metadata, error)
+	GetForFill(ctx context.Context, userID, listID, minScore int, lengths []int) ([]wordlist.Entry, error)
+	GetEntries(ctx context.Context, listID int) ([]wordlist.Entry, error)
+}
+
+type ClueRepository interface {
+	GetByAnswer(ctx context.Context, answer string, publishedOnly bool, filters Filters) ([]*ClueUse, Metadata, error)
+}
+
+type ImportRepository interface {
+	Insert(ctx context.Context, imp *Import) error
+	GetByID(ctx context.Context, id int) (*Import, error)
+	GetAllForOwner(ctx context.Context, ownerID int) ([]*Import, error)
+	GetByChecksum(ctx context.Context, ownerID int, checksum string) (*Import, error)
+	Update(ctx context.Context, imp *Import) error
+}
+
+type AuditRepository interface {
+	Insert(ctx context.Context, event *AuditEvent) error
+	List(ctx context.Context, filter AuditFilter, filters Filters) ([]*AuditEvent, Metadata, error)
+}
+
 type Models struct {
-	Users       UserModel
-	Permissions PermissionModel
-	Tokens      TokenModel
-	Puzzles     PuzzleModel
+	Users       UserRepository
+	Permissions PermissionRepository
+	Tokens      TokenRepository
+	Puzzles     PuzzleRepository
+	Authors     AuthorRepository
+	Tags        TagRepository
+	Collections CollectionRepository
+	WordLists   WordListRepository
+	Clues       ClueRepository
+	Imports     ImportRepository
+	Audit       AuditRepository
 }
 
-func NewModels(db *pgxpool.Pool) Models {
+// NewModels returns the models for db. Each query is given at most
+// queryTimeout, or DefaultQueryTimeout if it's zero, and each operation over
+// many rows at most bulkTimeout, or DefaultBulkTimeout if it's zero.
+func NewModels(db *pgxpool.Pool, queryTimeout, bulkTimeout time.Duration) Models {
 	return Models{
-		Users:       UserModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Puzzles:     PuzzleModel{DB: db},
+		Users:       UserModel{DB: db, Timeout: queryTimeout, BulkTimeout: bulkTimeout},
+		Tokens:      TokenModel{DB: db, Timeout: queryTimeout},
+		Permissions: PermissionModel{DB: db, Timeout: queryTimeout},
+		Puzzles:     PuzzleModel{DB: db, Timeout: queryTimeout},
+		Authors:     AuthorModel{DB: db, Timeout: queryTimeout},
+		Tags:        TagModel{DB: db, Timeout: queryTimeout},
+		Collections: CollectionModel{DB: db, Timeout: queryTimeout},
+		WordLists:   WordListModel{DB: db, Timeout: queryTimeout, BulkTimeout: bulkTimeout},
+		Clues:       ClueModel{DB: db, Timeout: queryTimeout},
+		Imports:     ImportModel{DB: db, Timeout: queryTimeout},
+		Audit:       AuditModel{DB: db, Timeout: queryTimeout},
 	}
 }
@@ -19,6 +19,16 @@ const (
 	UsersRead     = "users:read"
 	UsersUpdate   = "users:update"
 	UsersDelete   = "users:delete"
+
+	CollectionsCreate = "collections:create"
+	CollectionsUpdate = "collections:update"
+	CollectionsDelete = "collections:delete"
+
+	WordListsHouse = "wordlists:house"
+
+	// UsersAdmin lets support staff manage other users' accounts. Unlike the
+	// users:* codes above it isn't one of the StandardPermissions.
+	UsersAdmin = "users:admin"
 )
 
 var StandardPermissions = Permissions{
@@ -30,6 +40,9 @@ var StandardPermissions = Permissions{
 	UsersRead,
 	UsersUpdate,
 	UsersDelete,
+	CollectionsCreate,
+	CollectionsUpdate,
+	CollectionsDelete,
 }
 
 func (p Permissions) Include(code string) bool {
@@ -42,10 +55,11 @@ func (p Permissions) Include(code string) bool {
 }
 
 type PermissionModel struct {
-	DB *pgxpool.Pool
+	DB      *pgxpool.Pool
+	Timeout time.Duration
 }
 
-func (m PermissionModel) GetAllForUser(userID int) (Permissions, error) {
+func (m PermissionModel) GetAllForUser(ctx context.Context, userID int) (Permissions, error) {
 	query := `
 		SELECT permissions.code
 		FROM permissions
@@ -53,7 +67,7 @@ func (m PermissionModel) GetAllForUser(userID int) (Permissions, error) {
 		WHERE users_permissions.user_id = $1
 		ORDER BY permissions.code`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	rows, err := m.DB.Query(ctx, query, userID)
@@ -77,14 +91,14 @@ func (m PermissionModel) GetAllForUser(userID int) (Permissions, error) {
 	return permissions, nil
 }
 
-func (m PermissionModel) AddForUser(userID int, codes ...string) error {
+func (m PermissionModel) AddForUser(ctx context.Context, userID int, codes ...string) error {
 	query := `
 		INSERT INTO users_permissions
 		SELECT $1, permissions.id
 		FROM permissions
 		WHERE permissions.code = ANY($2)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	_, err := m.DB.Exec(ctx, query, userID, codes)
@@ -3,9 +3,16 @@ package data
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 	"unicode/utf8"
 
+	"github.com/ggetzie/badwords_be/internal/grid"
 	"github.com/ggetzie/badwords_be/internal/validator"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,26 +26,76 @@ type ClueData struct {
 }
 
 type PuzzleData struct {
-	Across map[string]ClueData `json:"across"`
-	Down   map[string]ClueData `json:"down"`
+	Across  map[string]ClueData `json:"across"`
+	Down    map[string]ClueData `json:"down"`
+	Circles []grid.Square       `json:"circles,omitempty"`
+}
+
+// Grid lays the answers out on a grid of the given size, with each clue's
+// zero-based row and column marking its first square. Squares that aren't
+// part of any answer are blocks and unknown letters are blank. Sizes beyond
+// grid.MaxSize are refused with grid.ErrSize before anything is allocated.
+func (d PuzzleData) Grid(width, height int) (*grid.Grid, error) {
+	if !grid.ValidSize(width, height) {
+		return nil, grid.ErrSize
+	}
+	g := grid.New(width, height)
+	for _, clue := range d.Across {
+		err := g.Place(grid.Across, clue.Row, clue.Col, clue.Answer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, clue := range d.Down {
+		err := g.Place(grid.Down, clue.Row, clue.Col, clue.Answer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// WithAnswers returns a copy of the puzzle data with every answer read back
+// from the grid.
+func (d PuzzleData) WithAnswers(g *grid.Grid) PuzzleData {
+	read := func(direction grid.Direction, clues map[string]ClueData) map[string]ClueData {
+		filled := make(map[string]ClueData, len(clues))
+		for number, clue := range clues {
+			clue.Answer = g.Word(grid.Entry{Direction: direction, Row: clue.Row, Col: clue.Col, Length: len(clue.Answer)})
+			filled[number] = clue
+		}
+		return filled
+	}
+	return PuzzleData{Across: read(grid.Across, d.Across), Down: read(grid.Down, d.Down), Circles: d.Circles}
 }
 
 type Puzzle struct {
-	ID          int        `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Content     PuzzleData `json:"content"`
-	Width       int        `json:"width"`
-	Height      int        `json:"height"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	Author      User       `json:"author"`
-	Published   bool       `json:"published"`
-	Version     int        `json:"-"`
+	ID               int        `json:"id"`
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	Content          PuzzleData `json:"content"`
+	Width            int        `json:"width"`
+	Height           int        `json:"height"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	Author           User       `json:"author"`
+	Published        bool       `json:"published"`
+	PublishedAt      *time.Time `json:"published_at"`
+	Tags             []string   `json:"tags"`
+	Theme            string     `json:"theme"`
+	Notes            string     `json:"notes"`
+	Difficulty       int        `json:"difficulty,omitempty"`
+	SolverDifficulty *float64   `json:"solver_difficulty"`
+	SolverVotes      int        `json:"solver_votes"`
+	SourceHash       string     `json:"-"`
+	Version          int        `json:"-"`
 }
 
+var ErrDuplicatePuzzle = errors.New("duplicate puzzle")
+
 type PuzzleModel struct {
-	DB *pgxpool.Pool
+	DB      *pgxpool.Pool
+	Timeout time.Duration
 }
 
 func ValidatePuzzle(v *validator.Validator, puzzle *Puzzle) {
@@ -48,19 +105,103 @@ func ValidatePuzzle(v *validator.Validator, puzzle *Puzzle) {
 	v.Check(puzzle.Description != "", "description", "must be provided")
 	v.Check(utf8.RuneCountInString(puzzle.Description) <= 1000, "description", "must not be more than 1000 characters long")
 
-	v.Check(puzzle.Width > 0, "width", "must be a positive integer")
-	v.Check(puzzle.Height > 0, "height", "must be a positive integer")
+	v.Check(puzzle.Width > 0 && puzzle.Width <= grid.MaxSize, "width", fmt.Sprintf("must be between 1 and %d", grid.MaxSize))
+	v.Check(puzzle.Height > 0 && puzzle.Height <= grid.MaxSize, "height", fmt.Sprintf("must be between 1 and %d", grid.MaxSize))
+
+	for _, circle := range puzzle.Content.Circles {
+		inBounds := circle.Row >= 0 && circle.Row < puzzle.Height && circle.Col >= 0 && circle.Col < puzzle.Width
+		v.Check(inBounds, "content", "circles must be inside the grid")
+	}
+
+	v.Check(utf8.RuneCountInString(puzzle.Theme) <= 200, "theme", "must not be more than 200 characters long")
+	v.Check(utf8.RuneCountInString(puzzle.Notes) <= 2000, "notes", "must not be more than 2000 characters long")
+	v.Check(puzzle.Difficulty == 0 || ValidDifficulty(puzzle.Difficulty), "difficulty", "must be between 1 and 5")
+
+	v.Check(len(puzzle.Tags) <= 10, "tags", "must not contain more than 10 tags")
+	v.Check(validator.Unique(puzzle.Tags), "tags", "must not contain duplicate values")
+	for _, tag := range puzzle.Tags {
+		v.Check(validator.Matches(tag, TagRX), "tags", "must only contain lowercase letters, digits and hyphens and be at most 50 characters long")
+	}
+}
+
+// ValidatePuzzleContent checks the clues of new or replaced content. It's kept
+// out of ValidatePuzzle so that puzzles saved before clue numbers were checked
+// can still be edited without resending their content.
+func ValidatePuzzleContent(v *validator.Validator, content PuzzleData) {
+	checkClueNumbers(v, "across", content.Across)
+	checkClueNumbers(v, "down", content.Down)
+}
+
+func checkClueNumbers(v *validator.Validator, direction string, clues map[string]ClueData) {
+	for _, key := range slices.Sorted(maps.Keys(clues)) {
+		if !validClueNumber(key) {
+			v.AddError("content", fmt.Sprintf("%s clue number %q must be a whole number from 1 to %d, written without leading zeros", direction, key, math.MaxInt32))
+		}
+	}
+}
+
+// validClueNumber reports whether a clue key is a number as the clues table
+// stores it. Keys like "01" and "+1" are refused since they'd be stored as
+// the same number as "1".
+func validClueNumber(key string) bool {
+	number, err := strconv.Atoi(key)
+	return err == nil && number >= 1 && number <= math.MaxInt32 && strconv.Itoa(number) == key
+}
+
+func ValidDifficulty(difficulty int) bool {
+	return difficulty >= 1 && difficulty <= 5
 }
 
-func (m PuzzleModel) Insert(puzzle *Puzzle) error {
+// setTags replaces the tags of a puzzle, creating any tags that don't exist
+// yet.
+func (m PuzzleModel) setTags(ctx context.Context, tx pgx.Tx, puzzleID int, tags []string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM puzzle_tags WHERE puzzle_id = $1`, puzzleID)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO tags (name)
+		SELECT unnest($1::text[])
+		ON CONFLICT (name) DO NOTHING`, tags)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO puzzle_tags (puzzle_id, tag_id)
+		SELECT $1, id FROM tags WHERE name = ANY($2)`, puzzleID, tags)
+	return err
+}
+
+func (m PuzzleModel) Insert(ctx context.Context, puzzle *Puzzle) error {
 	query := `
-		INSERT INTO puzzles (title, description, content, width, height, author_id, published, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-		RETURNING id, created_at, updated_at`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		INSERT INTO puzzles (title, description, content, width, height, author_id, published, published_at, theme, notes, difficulty, source_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, CASE WHEN $7 THEN NOW() END, $8, $9, NULLIF($10, 0), NULLIF($11, ''), NOW(), NOW())
+		RETURNING id, created_at, updated_at, published_at`
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRow(
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// clues are only rewritten when the content changes, so that puzzles
+	// saved with clue numbers that are refused now can still be edited
+	var contentChanged bool
+	err = tx.QueryRow(ctx, `SELECT content::jsonb IS DISTINCT FROM $2::jsonb FROM puzzles WHERE id = $1`,
+		puzzle.ID, puzzle.Content).Scan(&contentChanged)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+
+	err = tx.QueryRow(
 		ctx,
 		query,
 		puzzle.Title,
@@ -70,20 +211,47 @@ func (m PuzzleModel) Insert(puzzle *Puzzle) error {
 		puzzle.Height,
 		puzzle.Author.ID,
 		puzzle.Published,
-	).Scan(&puzzle.ID, &puzzle.CreatedAt, &puzzle.UpdatedAt)
+		puzzle.Theme,
+		puzzle.Notes,
+		puzzle.Difficulty,
+		puzzle.SourceHash,
+	).Scan(&puzzle.ID, &puzzle.CreatedAt, &puzzle.UpdatedAt, &puzzle.PublishedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "puzzles_author_source_hash_idx"):
+			return ErrDuplicatePuzzle
+		default:
+			return err
+		}
+	}
+
+	err = m.setTags(ctx, tx, puzzle.ID, puzzle.Tags)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	err = setClues(ctx, tx, puzzle.ID, puzzle.Content)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (m PuzzleModel) GetByID(id int) (*Puzzle, error) {
+// puzzleMetaColumns selects a puzzle's tags, theme, notes and difficulty
+// ratings for the puzzle aliased as p.
+const puzzleMetaColumns = `
+	ARRAY(SELECT t.name FROM puzzle_tags pt INNER JOIN tags t ON pt.tag_id = t.id WHERE pt.puzzle_id = p.id ORDER BY t.name),
+	p.theme, p.notes, COALESCE(p.difficulty, 0),
+	(SELECT avg(dv.rating)::float8 FROM difficulty_votes dv WHERE dv.puzzle_id = p.id),
+	(SELECT count(*) FROM difficulty_votes dv WHERE dv.puzzle_id = p.id)`
+
+func (m PuzzleModel) GetByID(ctx context.Context, id int) (*Puzzle, error) {
 	query := `
-		SELECT p.id, p.title, p.description, p.content, p.width, p.height, p.created_at, p.updated_at, p.published, p.version, u.id, u.full_name, u.display_name, u.email
+		SELECT p.id, p.title, p.description, p.content, p.width, p.height, p.created_at, p.updated_at, p.published, p.published_at, p.version, u.id, u.full_name, u.display_name, u.email,` + puzzleMetaColumns + `
 		FROM puzzles p
 		INNER JOIN users u ON p.author_id = u.id
 		WHERE p.id = $1`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	row := m.DB.QueryRow(ctx, query, id)
@@ -99,11 +267,18 @@ func (m PuzzleModel) GetByID(id int) (*Puzzle, error) {
 		&puzzle.CreatedAt,
 		&puzzle.UpdatedAt,
 		&puzzle.Published,
+		&puzzle.PublishedAt,
 		&puzzle.Version,
 		&puzzle.Author.ID,
 		&puzzle.Author.FullName,
 		&puzzle.Author.DisplayName,
 		&puzzle.Author.Email,
+		&puzzle.Tags,
+		&puzzle.Theme,
+		&puzzle.Notes,
+		&puzzle.Difficulty,
+		&puzzle.SolverDifficulty,
+		&puzzle.SolverVotes,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -114,16 +289,37 @@ func (m PuzzleModel) GetByID(id int) (*Puzzle, error) {
 	return puzzle, nil
 }
 
-func (m PuzzleModel) Update(puzzle *Puzzle) error {
+func (m PuzzleModel) Update(ctx context.Context, puzzle *Puzzle) error {
 	query := `
 		UPDATE puzzles
-		SET title = $1, description = $2, content = $3, width = $4, height = $5, published = $6, updated_at = NOW(), version = version + 1
-		WHERE id = $7 AND version = $8
-		RETURNING version, updated_at`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		SET title = $1, description = $2, content = $3, width = $4, height = $5, published = $6,
+			published_at = CASE WHEN NOT $6 THEN NULL ELSE COALESCE(published_at, NOW()) END,
+			theme = $7, notes = $8, difficulty = NULLIF($9, 0),
+			updated_at = NOW(), version = version + 1
+		WHERE id = $10 AND version = $11
+		RETURNING version, updated_at, published_at`
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
-	err := m.DB.QueryRow(
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// clues are only rewritten when the content changes, so that puzzles
+	// saved with clue numbers that are refused now can still be edited
+	var contentChanged bool
+	err = tx.QueryRow(ctx, `SELECT content::jsonb IS DISTINCT FROM $2::jsonb FROM puzzles WHERE id = $1`,
+		puzzle.ID, puzzle.Content).Scan(&contentChanged)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+
+	err = tx.QueryRow(
 		ctx,
 		query,
 		puzzle.Title,
@@ -132,29 +328,252 @@ func (m PuzzleModel) Update(puzzle *Puzzle) error {
 		puzzle.Width,
 		puzzle.Height,
 		puzzle.Published,
+		puzzle.Theme,
+		puzzle.Notes,
+		puzzle.Difficulty,
 		puzzle.ID,
 		puzzle.Version,
-	).Scan(&puzzle.Version, &puzzle.UpdatedAt)
+	).Scan(&puzzle.Version, &puzzle.UpdatedAt, &puzzle.PublishedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrEditConflict
 		}
 		return err
 	}
-	return nil
+
+	err = m.setTags(ctx, tx, puzzle.ID, puzzle.Tags)
+	if err != nil {
+		return err
+	}
+
+	if contentChanged {
+		err = setClues(ctx, tx, puzzle.ID, puzzle.Content)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+type PuzzleExportFilter struct {
+	AuthorID      int
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
 }
 
-func (m PuzzleModel) Delete(id int) error {
+// Export calls fn with each puzzle matching the filter, oldest first, reading
+// them from the database one at a time so the whole archive is never held in
+// memory. Puzzles whose author has been deleted are included without one.
+// It stops at the first error from fn.
+func (m PuzzleModel) Export(ctx context.Context, filter PuzzleExportFilter, fn func(*Puzzle) error) error {
+	query := `
+		SELECT p.id, p.title, p.description, p.content, p.width, p.height, p.created_at, p.updated_at, p.published, p.published_at, p.version,
+			COALESCE(u.id, 0), COALESCE(u.full_name, ''), COALESCE(u.display_name, ''),` + puzzleMetaColumns + `
+		FROM puzzles p
+		LEFT JOIN users u ON p.author_id = u.id
+		WHERE (p.author_id = $1 OR $1 = 0)
+		AND (p.created_at >= $2 OR $2 IS NULL)
+		AND (p.created_at < $3 OR $3 IS NULL)
+		ORDER BY p.created_at, p.id`
+
+	rows, err := m.DB.Query(ctx, query, filter.AuthorID, nullTime(filter.CreatedAfter), nullTime(filter.CreatedBefore))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var puzzle Puzzle
+		err := rows.Scan(
+			&puzzle.ID,
+			&puzzle.Title,
+			&puzzle.Description,
+			&puzzle.Content,
+			&puzzle.Width,
+			&puzzle.Height,
+			&puzzle.CreatedAt,
+			&puzzle.UpdatedAt,
+			&puzzle.Published,
+			&puzzle.PublishedAt,
+			&puzzle.Version,
+			&puzzle.Author.ID,
+			&puzzle.Author.FullName,
+			&puzzle.Author.DisplayName,
+			&puzzle.Tags,
+			&puzzle.Theme,
+			&puzzle.Notes,
+			&puzzle.Difficulty,
+			&puzzle.SolverDifficulty,
+			&puzzle.SolverVotes,
+		)
+		if err != nil {
+			return err
+		}
+		err = fn(&puzzle)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type DifficultyVote struct {
+	PuzzleID  int       `json:"puzzle_id"`
+	Rating    int       `json:"rating"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (m PuzzleModel) GetDifficultyVotesForUser(ctx context.Context, userID int) ([]DifficultyVote, error) {
+	query := `
+		SELECT puzzle_id, rating, created_at
+		FROM difficulty_votes
+		WHERE user_id = $1
+		ORDER BY created_at`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []DifficultyVote{}
+	for rows.Next() {
+		var vote DifficultyVote
+		err := rows.Scan(&vote.PuzzleID, &vote.Rating, &vote.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// GetIDBySourceHash finds the puzzle an author imported from a file with the
+// given hash.
+func (m PuzzleModel) GetIDBySourceHash(ctx context.Context, authorID int, hash string) (int, error) {
+	query := `
+		SELECT id
+		FROM puzzles
+		WHERE author_id = $1 AND source_hash = $2`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	var id int
+	err := m.DB.QueryRow(ctx, query, authorID, hash).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// SetDifficultyVote records a solver's difficulty rating for a puzzle,
+// replacing any rating they gave it before.
+func (m PuzzleModel) SetDifficultyVote(ctx context.Context, puzzleID, userID, rating int) error {
+	query := `
+		INSERT INTO difficulty_votes (puzzle_id, user_id, rating)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (puzzle_id, user_id) DO UPDATE SET rating = EXCLUDED.rating, created_at = NOW()`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, query, puzzleID, userID, rating)
+	return err
+}
+
+func (m PuzzleModel) Delete(ctx context.Context, id int) error {
 	query := `
 		DELETE FROM puzzles
 		WHERE id = $1`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	_, err := m.DB.Exec(ctx, query, id)
 	return err
 }
 
+// PublishedStates reports whether each of the given puzzles is published.
+// Puzzles that don't exist are missing from the returned map.
+func (m PuzzleModel) PublishedStates(ctx context.Context, ids []int) (map[int]bool, error) {
+	query := `
+		SELECT id, published
+		FROM puzzles
+		WHERE id = ANY($1)`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, nonNil(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[int]bool, len(ids))
+	for rows.Next() {
+		var id int
+		var published bool
+		err := rows.Scan(&id, &published)
+		if err != nil {
+			return nil, err
+		}
+		states[id] = published
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// AnswerUse records a published puzzle that used an answer.
+type AnswerUse struct {
+	Answer      string     `json:"answer"`
+	PuzzleID    int        `json:"puzzle_id"`
+	Title       string     `json:"title"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// AnswerUses finds the published puzzles other than excludeID that used any
+// of the given answers, most recent first.
+func (m PuzzleModel) AnswerUses(ctx context.Context, answers []string, excludeID int) ([]AnswerUse, error) {
+	query := `
+		SELECT c.answer, p.id, p.title, p.published_at
+		FROM clues c
+		INNER JOIN puzzles p ON c.puzzle_id = p.id
+		WHERE p.published AND p.id <> $2 AND c.answer = ANY($1)
+		ORDER BY c.answer, p.published_at DESC NULLS LAST, p.id DESC`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, nonNil(answers), excludeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uses := []AnswerUse{}
+	for rows.Next() {
+		var use AnswerUse
+		err := rows.Scan(&use.Answer, &use.PuzzleID, &use.Title, &use.PublishedAt)
+		if err != nil {
+			return nil, err
+		}
+		uses = append(uses, use)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return uses, nil
+}
+
 func GetPublished(publishedVal string) (published1, published2 bool) {
 	if publishedVal == "false" {
 		return false, false
@@ -166,17 +585,235 @@ func GetPublished(publishedVal string) (published1, published2 bool) {
 	return true, true
 }
 
-func (m PuzzleModel) List(published1, published2 bool, filters Filters) ([]*Puzzle, Metadata, error) {
-	query := `
-		SELECT count(*) OVER(), p.id, p.title, p.description, p.content, p.width, p.height, p.created_at, p.updated_at, p.published, p.version, u.id, u.full_name, u.display_name, u.email
+type PuzzleSearch struct {
+	Query         string
+	AuthorID      int
+	Width         int
+	Height        int
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Tags          []string
+	Difficulties  []int
+}
+
+func ValidatePuzzleSearch(v *validator.Validator, search PuzzleSearch) {
+	v.Check(utf8.RuneCountInString(search.Query) <= 200, "q", "must not be more than 200 characters long")
+	v.Check(search.AuthorID >= 0, "author", "must be a positive integer")
+	v.Check(search.Width >= 0, "size", "must be a positive integer")
+	v.Check(search.Height >= 0, "size", "must be a positive integer")
+	if !search.CreatedAfter.IsZero() && !search.CreatedBefore.IsZero() {
+		v.Check(!search.CreatedBefore.Before(search.CreatedAfter), "created_before", "must not be earlier than created_after")
+	}
+	for _, tag := range search.Tags {
+		v.Check(validator.Matches(tag, TagRX), "tag", "must be a valid tag")
+	}
+}
+
+// nonNil returns an empty slice in place of nil so that optional array
+// filters are sent to PostgreSQL as empty arrays rather than NULL.
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
+
+// nullTime returns nil for the zero time so that optional date filters are
+// sent to PostgreSQL as NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+var PuzzleFieldSafeList = []string{
+	"id", "title", "description", "content", "width", "height", "author",
+	"created_at", "updated_at", "published", "published_at",
+	"tags", "theme", "notes", "difficulty", "solver_difficulty",
+}
+
+// PuzzleSummaryFields are the fields returned by puzzle listings unless the
+// caller asks for others. They leave out the clues so an archive page
+// doesn't download every puzzle's content.
+var PuzzleSummaryFields = []string{
+	"id", "title", "width", "height", "author", "created_at", "updated_at", "published", "published_at",
+	"tags", "difficulty",
+}
+
+func ValidatePuzzleFields(v *validator.Validator, fields []string) {
+	for _, field := range fields {
+		v.Check(validator.PermittedValue(field, PuzzleFieldSafeList...), "fields", "invalid field "+field)
+	}
+	v.Check(validator.Unique(fields), "fields", "must not contain duplicate values")
+}
+
+// Select returns the requested fields of the puzzle keyed by their JSON
+// names. The author is reduced to their public identity, plus their email
+// when it hasn't been cleared for the viewer.
+func (p *Puzzle) Select(fields []string) map[string]any {
+	selected := make(map[string]any, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			selected[field] = p.ID
+		case "title":
+			selected[field] = p.Title
+		case "description":
+			selected[field] = p.Description
+		case "content":
+			selected[field] = p.Content
+		case "width":
+			selected[field] = p.Width
+		case "height":
+			selected[field] = p.Height
+		case "author":
+			author := map[string]any{"id": p.Author.ID, "display_name": p.Author.DisplayName}
+			if p.Author.Email != "" {
+				author["email"] = p.Author.Email
+			}
+			selected[field] = author
+		case "created_at":
+			selected[field] = p.CreatedAt
+		case "updated_at":
+			selected[field] = p.UpdatedAt
+		case "published":
+			selected[field] = p.Published
+		case "published_at":
+			selected[field] = p.PublishedAt
+		case "tags":
+			selected[field] = p.Tags
+		case "theme":
+			selected[field] = p.Theme
+		case "notes":
+			selected[field] = p.Notes
+		case "difficulty":
+			selected[field] = p.Difficulty
+		case "solver_difficulty":
+			selected[field] = map[string]any{"average": p.SolverDifficulty, "votes": p.SolverVotes}
+		}
+	}
+	return selected
+}
+
+// puzzleSortKey describes how a permitted sort column is ordered in SQL, the
+// type its cursor value is cast to, and how that value is read from a puzzle.
+// Unpublished puzzles have no publish date and sort by their creation date.
+type puzzleSortKey struct {
+	expr  string
+	cast  string
+	value func(p *Puzzle) string
+}
+
+var puzzleSortKeys = map[string]puzzleSortKey{
+	"title": {"p.title", "text", func(p *Puzzle) string {
+		return p.Title
+	}},
+	"created_at": {"p.created_at", "timestamptz", func(p *Puzzle) string {
+		return p.CreatedAt.Format(time.RFC3339Nano)
+	}},
+	"updated_at": {"p.updated_at", "timestamptz", func(p *Puzzle) string {
+		return p.UpdatedAt.Format(time.RFC3339Nano)
+	}},
+	"published_at": {"COALESCE(p.published_at, p.created_at)", "timestamptz", func(p *Puzzle) string {
+		if p.PublishedAt != nil {
+			return p.PublishedAt.Format(time.RFC3339Nano)
+		}
+		return p.CreatedAt.Format(time.RFC3339Nano)
+	}},
+	"size": {"p.width * p.height", "int", func(p *Puzzle) string {
+		return strconv.Itoa(p.Width * p.Height)
+	}},
+	"relevance": {"ts_rank(p.search, websearch_to_tsquery('english', $3))", "", nil},
+}
+
+var PuzzleSortSafeList = []string{
+	"title", "created_at", "updated_at", "published_at", "size", "relevance",
+	"-title", "-created_at", "-updated_at", "-published_at", "-size", "-relevance",
+}
+
+// PuzzleSortSupportsCursor reports whether the given sort can be paged with
+// an after cursor. Relevance ranks are floating point scores and are only
+// paged by page number.
+func PuzzleSortSupportsCursor(sort string) bool {
+	return puzzleSortKeys[strings.TrimPrefix(sort, "-")].cast != ""
+}
+
+func (m PuzzleModel) List(ctx context.Context, published1, published2 bool, search PuzzleSearch, fields []string, filters Filters) ([]*Puzzle, Metadata, error) {
+	key := puzzleSortKeys[filters.sortColumn()]
+	direction := filters.sortDirection()
+
+	// the description and content are only loaded when they will be returned
+	description := "''"
+	if slices.Contains(fields, "description") {
+		description = "p.description"
+	}
+	content := "'{}'::json"
+	if slices.Contains(fields, "content") {
+		content = "p.content"
+	}
+
+	args := []any{
+		published1,
+		published2,
+		search.Query,
+		search.AuthorID,
+		search.Width,
+		search.Height,
+		nullTime(search.CreatedAfter),
+		nullTime(search.CreatedBefore),
+		nonNil(search.Difficulties),
+		nonNil(search.Tags),
+	}
+
+	// With an after cursor the page starts immediately past the row the
+	// cursor points at instead of at an offset, and the total count is
+	// skipped because it would only count the remaining rows.
+	count := "count(*) OVER()"
+	keyset := ""
+	offset := filters.offset()
+	if filters.After != "" {
+		c, err := decodeCursor(filters.After)
+		if err != nil || c.Sort != filters.Sort || key.cast == "" {
+			return nil, Metadata{}, ErrInvalidCursor
+		}
+		comparison := ">"
+		if direction == "DESC" {
+			comparison = "<"
+		}
+		args = append(args, c.Value, c.ID)
+		keyset = fmt.Sprintf("AND (%s, p.id) %s ($%d::%s, $%d)", key.expr, comparison, len(args)-1, key.cast, len(args))
+		count = "0"
+		offset = 0
+	}
+	args = append(args, filters.limit(), offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s, p.id, p.title, %s, %s, p.width, p.height, p.created_at, p.updated_at, p.published, p.published_at, p.version, u.id, u.full_name, u.display_name, u.email,`+puzzleMetaColumns+`
 		FROM puzzles p
 		INNER JOIN users u ON p.author_id = u.id
-		WHERE p.published = $1 OR p.published = $2
-		ORDER BY p.updated_at DESC
-		LIMIT $3 OFFSET $4`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		WHERE (p.published = $1 OR p.published = $2)
+		AND (p.search @@ websearch_to_tsquery('english', $3) OR $3 = '')
+		AND (p.author_id = $4 OR $4 = 0)
+		AND (p.width = $5 OR $5 = 0)
+		AND (p.height = $6 OR $6 = 0)
+		AND ($7::timestamptz IS NULL OR p.created_at >= $7)
+		AND ($8::timestamptz IS NULL OR p.created_at < $8)
+		AND (cardinality($9::int[]) = 0 OR p.difficulty = ANY($9))
+		AND (cardinality($10::text[]) = 0 OR p.id IN (
+			SELECT pt.puzzle_id
+			FROM puzzle_tags pt
+			INNER JOIN tags t ON pt.tag_id = t.id
+			WHERE t.name = ANY($10)
+			GROUP BY pt.puzzle_id
+			HAVING count(*) = cardinality($10)))
+		%s
+		ORDER BY %s %s, p.id %s
+		LIMIT $%d OFFSET $%d`, count, description, content, keyset, key.expr, direction, direction, len(args)-1, len(args))
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
-	rows, err := m.DB.Query(ctx, query, published1, published2, filters.PageSize, filters.offset())
+	rows, err := m.DB.Query(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -198,11 +835,18 @@ func (m PuzzleModel) List(published1, published2 bool, filters Filters) ([]*Puzz
 			&puzzle.CreatedAt,
 			&puzzle.UpdatedAt,
 			&puzzle.Published,
+			&puzzle.PublishedAt,
 			&puzzle.Version,
 			&puzzle.Author.ID,
 			&puzzle.Author.FullName,
 			&puzzle.Author.DisplayName,
 			&puzzle.Author.Email,
+			&puzzle.Tags,
+			&puzzle.Theme,
+			&puzzle.Notes,
+			&puzzle.Difficulty,
+			&puzzle.SolverDifficulty,
+			&puzzle.SolverVotes,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -212,6 +856,16 @@ func (m PuzzleModel) List(published1, published2 bool, filters Filters) ([]*Puzz
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	var metadata Metadata
+	if filters.After != "" {
+		metadata = Metadata{PageSize: filters.PageSize}
+	} else {
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	}
+	if key.cast != "" && len(puzzles) == filters.limit() {
+		last := puzzles[len(puzzles)-1]
+		metadata.NextCursor = encodeCursor(cursor{Sort: filters.Sort, Value: key.value(last), ID: last.ID})
+	}
 	return puzzles, metadata, nil
 }
@@ -0,0 +1,40 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/ggetzie/badwords_be/internal/validator"
+)
+
+func TestValidatePuzzleContent(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want bool
+	}{
+		{"Numbers", []string{"1", "12"}, true},
+		{"Largest number", []string{"2147483647"}, true},
+		{"Zero", []string{"0"}, false},
+		{"Negative", []string{"-1"}, false},
+		{"Not a number", []string{"1a"}, false},
+		{"Too large", []string{"2147483648"}, false},
+		{"Far too large", []string{"99999999999999999999"}, false},
+		{"Leading zero", []string{"1", "01"}, false},
+		{"Plus sign", []string{"1", "+1"}, false},
+		{"Spaces", []string{" 1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := PuzzleData{Across: map[string]ClueData{}}
+			for _, key := range tt.keys {
+				content.Across[key] = ClueData{Clue: "Feline", Answer: "CAT"}
+			}
+			v := validator.New()
+			ValidatePuzzleContent(v, content)
+			if v.Valid() != tt.want {
+				t.Errorf("got valid %t (%v); want %t", v.Valid(), v.Errors, tt.want)
+			}
+		})
+	}
+}
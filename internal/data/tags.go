@@ -0,0 +1,71 @@
+package data
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var TagRX = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+type Tag struct {
+	Name    string `json:"name"`
+	Puzzles int    `json:"puzzles"`
+}
+
+// NormalizeTags lowercases tags and replaces inner whitespace with hyphens so
+// that "Monday Themeless" and "monday-themeless" are the same tag.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+type TagModel struct {
+	DB      *pgxpool.Pool
+	Timeout time.Duration
+}
+
+// GetAll returns every tag used by at least one published puzzle along with
+// the number of published puzzles carrying it.
+func (m TagModel) GetAll(ctx context.Context) ([]*Tag, error) {
+	query := `
+		SELECT t.name, count(*)
+		FROM tags t
+		INNER JOIN puzzle_tags pt ON t.id = pt.tag_id
+		INNER JOIN puzzles p ON pt.puzzle_id = p.id
+		WHERE p.published
+		GROUP BY t.name
+		ORDER BY count(*) DESC, t.name`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		var tag Tag
+		err := rows.Scan(&tag.Name, &tag.Puzzles)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
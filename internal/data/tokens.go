@@ -26,7 +26,8 @@ type Token struct {
 }
 
 type TokenModel struct {
-	DB *pgxpool.Pool
+	DB      *pgxpool.Pool
+	Timeout time.Duration
 }
 
 func generateToken(userID int, ttl time.Duration, scope string) (*Token, error) {
@@ -53,48 +54,48 @@ func ValidateTokenPlaintext(v *validator.Validator, input string) {
 	v.Check(len(input) == 26, "token", "must be 26 bytes long")
 }
 
-func (m TokenModel) New(userID int, ttl time.Duration, scope string) (*Token, error) {
+func (m TokenModel) New(ctx context.Context, userID int, ttl time.Duration, scope string) (*Token, error) {
 
 	token, err := generateToken(userID, ttl, scope)
 	if err != nil {
 		return nil, err
 	}
-	err = m.Insert(token)
+	err = m.Insert(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 	return token, nil
 }
 
-func (m TokenModel) Insert(token *Token) error {
+func (m TokenModel) Insert(ctx context.Context, token *Token) error {
 	query := `
 		INSERT INTO tokens (hash, user_id, expiry, scope)
 		VALUES ($1, $2, $3, $4)`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
 	_, err := m.DB.Exec(ctx, query, args...)
 	return err
 }
 
-func (m TokenModel) DeleteAllForUser(scope string, userID int) error {
+func (m TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int) error {
 	query := `
 		DELETE FROM tokens
 		WHERE scope = $1 AND user_id = $2`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 	_, err := m.DB.Exec(ctx, query, scope, userID)
 	return err
 }
 
-func (m TokenModel) GetForText(plainText, scope string) (*Token, error) {
+func (m TokenModel) GetForText(ctx context.Context, plainText, scope string) (*Token, error) {
 	hash := sha256.Sum256([]byte(plainText))
 	query := `
 		SELECT hash, user_id, expiry, scope
 		FROM tokens
 		WHERE hash = $1 AND scope = $2 AND expiry > now()`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 	row := m.DB.QueryRow(ctx, query, hash, scope)
 	token := &Token{}
@@ -102,14 +103,50 @@ func (m TokenModel) GetForText(plainText, scope string) (*Token, error) {
 	return token, err
 }
 
-func (m TokenModel) DeleteForText(plainText string) error {
+func (m TokenModel) DeleteForText(ctx context.Context, plainText string) error {
 	hash := sha256.Sum256([]byte(plainText))
 	query := `
 		DELETE FROM tokens
 		WHERE hash = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 	_, err := m.DB.Exec(ctx, query, hash[:])
 	return err
 }
+
+// Session describes a token without revealing it.
+type Session struct {
+	Scope  string    `json:"scope"`
+	Expiry time.Time `json:"expiry"`
+}
+
+func (m TokenModel) GetAllForUser(ctx context.Context, userID int) ([]Session, error) {
+	query := `
+		SELECT scope, expiry
+		FROM tokens
+		WHERE user_id = $1
+		ORDER BY expiry`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(&session.Scope, &session.Expiry)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
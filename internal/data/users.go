@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -16,12 +17,14 @@ import (
 
 type User struct {
 	ID          int       `json:"id"`
-	Email       string    `json:"email"`
+	Email       string    `json:"email,omitempty"`
 	Password    password  `json:"-"`
 	CreatedAt   time.Time `json:"created_at"`
 	Version     int       `json:"-"`
 	FullName    string    `json:"full_name"`
 	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
 	Activated   bool      `json:"activated"`
 }
 
@@ -81,6 +84,11 @@ func ValidateUser(v *validator.Validator, user *User) {
 	v.Check(utf8.RuneCountInString(user.FullName) <= 200, "full_name", "must not be more than 200 characters")
 	// check DisplayName is less than 200 runes
 	v.Check(utf8.RuneCountInString(user.DisplayName) <= 200, "display_name", "must not be more than 200 characters")
+	v.Check(utf8.RuneCountInString(user.Bio) <= 2000, "bio", "must not be more than 2000 characters")
+	if user.AvatarURL != "" {
+		v.Check(validator.ValidURL(user.AvatarURL), "avatar_url", "must be a valid http or https URL")
+		v.Check(len(user.AvatarURL) <= 2000, "avatar_url", "must not be more than 2000 bytes long")
+	}
 }
 
 var (
@@ -89,22 +97,26 @@ var (
 )
 
 type UserModel struct {
-	DB *pgxpool.Pool
+	DB          *pgxpool.Pool
+	Timeout     time.Duration
+	BulkTimeout time.Duration
 }
 
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(ctx context.Context, user *User) error {
 	query := `
-		INSERT INTO users (email, password_hash, full_name, display_name, activated	)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (email, password_hash, full_name, display_name, bio, avatar_url, activated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, version`
 	args := []any{
 		strings.Trim(user.Email, " "),
 		user.Password.hash,
 		strings.Trim(user.FullName, " "),
 		strings.Trim(user.DisplayName, " "),
+		strings.Trim(user.Bio, " "),
+		strings.Trim(user.AvatarURL, " "),
 		user.Activated,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 	err := m.DB.QueryRow(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
 	if err != nil {
@@ -120,22 +132,24 @@ func (m UserModel) Insert(user *User) error {
 	return nil
 }
 
-func (m UserModel) Update(user *User) error {
+func (m UserModel) Update(ctx context.Context, user *User) error {
 	query := `
 		UPDATE users
-		SET full_name = $1, display_name = $2, email = $3, password_hash = $4, activated = $5, version = version + 1
-		WHERE id = $6 AND version = $7
+		SET full_name = $1, display_name = $2, email = $3, password_hash = $4, bio = $5, avatar_url = $6, activated = $7, version = version + 1
+		WHERE id = $8 AND version = $9
 		RETURNING version`
 	args := []any{
 		strings.Trim(user.FullName, " "),
 		strings.Trim(user.DisplayName, " "),
 		strings.Trim(user.Email, " "),
 		user.Password.hash,
+		strings.Trim(user.Bio, " "),
+		strings.Trim(user.AvatarURL, " "),
 		user.Activated,
 		user.ID,
 		user.Version,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 	err := m.DB.QueryRow(ctx, query, args...).Scan(&user.Version)
 	if err != nil {
@@ -153,19 +167,19 @@ func (m UserModel) Update(user *User) error {
 	return nil
 }
 
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, created_at, full_name, display_name, email, password_hash, activated, version
+		SELECT id, created_at, full_name, display_name, bio, avatar_url, email, password_hash, activated, version
 		FROM users
 		WHERE email = $1`
 
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	row := m.DB.QueryRow(ctx, query, email)
 	err := row.Scan(
-		&user.ID, &user.CreatedAt, &user.FullName, &user.DisplayName,
+		&user.ID, &user.CreatedAt, &user.FullName, &user.DisplayName, &user.Bio, &user.AvatarURL,
 		&user.Email, &user.Password.hash, &user.Activated, &user.Version)
 	if err != nil {
 		switch {
@@ -178,11 +192,11 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-	SELECT users.id, users.created_at, users.full_name, users.display_name, users.email, users.password_hash, users.activated, users.version
+	SELECT users.id, users.created_at, users.full_name, users.display_name, users.bio, users.avatar_url, users.email, users.password_hash, users.activated, users.version
 	FROM users
 	INNER JOIN tokens
 	ON users.id = tokens.user_id
@@ -193,7 +207,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	args := []any{tokenHash[:], tokenScope, time.Now()}
 
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRow(ctx, query, args...).Scan(
@@ -201,6 +215,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.CreatedAt,
 		&user.FullName,
 		&user.DisplayName,
+		&user.Bio,
+		&user.AvatarURL,
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
@@ -218,19 +234,19 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	return &user, nil
 }
 
-func (m UserModel) GetByID(id int) (*User, error) {
+func (m UserModel) GetByID(ctx context.Context, id int) (*User, error) {
 	query := `
-		SELECT id, created_at, full_name, display_name, email, password_hash, activated, version
+		SELECT id, created_at, full_name, display_name, bio, avatar_url, email, password_hash, activated, version
 		FROM users
 		WHERE id = $1`
 
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	row := m.DB.QueryRow(ctx, query, id)
 	err := row.Scan(
-		&user.ID, &user.CreatedAt, &user.FullName, &user.DisplayName,
+		&user.ID, &user.CreatedAt, &user.FullName, &user.DisplayName, &user.Bio, &user.AvatarURL,
 		&user.Email, &user.Password.hash, &user.Activated, &user.Version)
 	if err != nil {
 		switch {
@@ -242,3 +258,138 @@ func (m UserModel) GetByID(id int) (*User, error) {
 	}
 	return &user, nil
 }
+
+// DeletedUserEmail identifies the placeholder author that published puzzles
+// are handed to when their author's account is deleted.
+const DeletedUserEmail = "deleted-user"
+
+// Delete removes a user's account along with their draft puzzles and
+// unpublished collections. Their published puzzles and collections are
+// deleted too if deletePuzzles is set, and otherwise handed to the
+// placeholder author so they stay available to solvers. Everything else the
+// user owns is removed by the database's cascading deletes.
+func (m UserModel) Delete(ctx context.Context, id int, deletePuzzles bool) error {
+	ctx, cancel := bulkContext(ctx, m.BulkTimeout)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM puzzles WHERE author_id = $1 AND (NOT published OR $2)`, id, deletePuzzles)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE puzzles
+		SET author_id = (SELECT id FROM users WHERE email = $2), updated_at = NOW(), version = version + 1
+		WHERE author_id = $1`,
+		id, DeletedUserEmail)
+	if err != nil {
+		return err
+	}
+
+	// collections would otherwise be kept without an owner
+	_, err = tx.Exec(ctx, `DELETE FROM collections WHERE owner_id = $1 AND (NOT published OR $2)`, id, deletePuzzles)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE collections
+		SET owner_id = (SELECT id FROM users WHERE email = $2), updated_at = NOW(), version = version + 1
+		WHERE owner_id = $1`,
+		id, DeletedUserEmail)
+	if err != nil {
+		return err
+	}
+
+	// the audit log keeps what the user did but not who they were or where
+	// they did it from, including the address typed in at failed logins
+	_, err = tx.Exec(ctx, `UPDATE audit_events SET actor_email = '', ip = '' WHERE actor_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE audit_events
+		SET details = details - 'email'
+		WHERE (target_type = 'user' AND target_id = $1)
+		OR lower(details->>'email') = (SELECT lower(email) FROM users WHERE id = $1)`, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1 AND email <> $2`, id, DeletedUserEmail)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+type UserSearch struct {
+	Query     string
+	Activated string
+}
+
+var UserSortSafeList = []string{"id", "email", "display_name", "created_at", "-id", "-email", "-display_name", "-created_at"}
+
+func ValidateUserSearch(v *validator.Validator, search UserSearch) {
+	v.Check(utf8.RuneCountInString(search.Query) <= 200, "q", "must not be more than 200 characters long")
+	v.Check(validator.PermittedValue(search.Activated, "true", "false", "all"), "activated", "must be true, false or all")
+}
+
+// List finds users whose email, full name or display name contains the
+// search query. The placeholder author is left out.
+func (m UserModel) List(ctx context.Context, search UserSearch, filters Filters) ([]*User, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, full_name, display_name, bio, avatar_url, email, activated, version
+		FROM users
+		WHERE email <> $1
+		AND (email ILIKE $2 OR full_name ILIKE $2 OR display_name ILIKE $2)
+		AND (activated = $3 OR $4)
+		ORDER BY %s %s, id ASC
+		LIMIT $5 OFFSET $6`, filters.sortColumn(), filters.sortDirection())
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	pattern := "%" + likeEscaper.Replace(search.Query) + "%"
+	args := []any{DeletedUserEmail, pattern, search.Activated == "true", search.Activated == "all", filters.limit(), filters.offset()}
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	totalRecords := 0
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.CreatedAt,
+			&user.FullName,
+			&user.DisplayName,
+			&user.Bio,
+			&user.AvatarURL,
+			&user.Email,
+			&user.Activated,
+			&user.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		users = append(users, &user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return users, metadata, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
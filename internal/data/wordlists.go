@@ -0,0 +1,356 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ggetzie/badwords_be/internal/validator"
+	"github.com/ggetzie/badwords_be/internal/wordlist"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WordList is a constructor's private list of words, or the shared house
+// list when it has no owner.
+type WordList struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   int       `json:"owner_id,omitempty"`
+	Shared    bool      `json:"shared"`
+	WordCount int       `json:"word_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Word struct {
+	wordlist.Entry
+	ListID int `json:"list_id"`
+}
+
+type WordLookup struct {
+	Pattern  string
+	MinScore int
+	ListID   int
+}
+
+type WordListModel struct {
+	DB          *pgxpool.Pool
+	Timeout     time.Duration
+	BulkTimeout time.Duration
+}
+
+func ValidateWordList(v *validator.Validator, list *WordList) {
+	v.Check(list.Name != "", "name", "must be provided")
+	v.Check(utf8.RuneCountInString(list.Name) <= 100, "name", "must not be more than 100 characters long")
+}
+
+func ValidateWordEntry(v *validator.Validator, entry wordlist.Entry) {
+	v.Check(wordlist.ValidWord(entry.Word), "word", "must contain between 1 and 25 letters")
+	v.Check(wordlist.ValidScore(entry.Score), "score", "must be between 0 and 100")
+	v.Check(utf8.RuneCountInString(entry.Notes) <= wordlist.MaxNotesLen, "notes", "must not be more than 500 characters long")
+}
+
+func ValidateWordLookup(v *validator.Validator, lookup WordLookup) {
+	v.Check(lookup.Pattern != "", "pattern", "must be provided")
+	v.Check(validator.Matches(lookup.Pattern, wordlist.PatternRX), "pattern", "must contain up to 25 letters and ? for unknown squares")
+	v.Check(wordlist.ValidScore(lookup.MinScore), "min_score", "must be between 0 and 100")
+	v.Check(lookup.ListID >= 0, "list", "must be a positive integer")
+}
+
+// CanRead reports whether a user may look words up in the list.
+func (l *WordList) CanRead(user *User) bool {
+	return l.Shared || l.OwnerID == user.ID
+}
+
+const wordListColumns = `
+	l.id, l.name, COALESCE(l.owner_id, 0), l.owner_id IS NULL, l.created_at, l.updated_at,
+	(SELECT count(*) FROM words w WHERE w.list_id = l.id)`
+
+func scanWordList(row pgx.Row) (*WordList, error) {
+	var list WordList
+	err := row.Scan(
+		&list.ID,
+		&list.Name,
+		&list.OwnerID,
+		&list.Shared,
+		&list.CreatedAt,
+		&list.UpdatedAt,
+		&list.WordCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (m WordListModel) Insert(ctx context.Context, list *WordList) error {
+	query := `
+		INSERT INTO word_lists (name, owner_id)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRow(ctx, query, list.Name, list.OwnerID).Scan(&list.ID, &list.CreatedAt, &list.UpdatedAt)
+}
+
+func (m WordListModel) GetByID(ctx context.Context, id int) (*WordList, error) {
+	query := `
+		SELECT ` + wordListColumns + `
+		FROM word_lists l
+		WHERE l.id = $1`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	list, err := scanWordList(m.DB.QueryRow(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// GetAllForUser returns the shared house list followed by the user's own
+// lists.
+func (m WordListModel) GetAllForUser(ctx context.Context, userID int) ([]*WordList, error) {
+	query := `
+		SELECT ` + wordListColumns + `
+		FROM word_lists l
+		WHERE l.owner_id IS NULL OR l.owner_id = $1
+		ORDER BY l.owner_id NULLS FIRST, l.name, l.id`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []*WordList{}
+	for rows.Next() {
+		list, err := scanWordList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+func (m WordListModel) Delete(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM word_lists
+		WHERE id = $1`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// AddWords adds entries to a list, updating the score and notes of words
+// already in it. When replace is set the list is emptied first.
+func (m WordListModel) AddWords(ctx context.Context, listID int, entries []wordlist.Entry, replace bool) error {
+	words := make([]string, len(entries))
+	scores := make([]int, len(entries))
+	notes := make([]string, len(entries))
+	for i, entry := range entries {
+		words[i] = entry.Word
+		scores[i] = entry.Score
+		notes[i] = entry.Notes
+	}
+
+	// large uploads take longer than a single row change
+	ctx, cancel := bulkContext(ctx, m.BulkTimeout)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if replace {
+		_, err = tx.Exec(ctx, `DELETE FROM words WHERE list_id = $1`, listID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO words (list_id, word, score, notes)
+		SELECT $1, w.word, w.score, w.notes
+		FROM unnest($2::text[], $3::int[], $4::text[]) AS w(word, score, notes)
+		ON CONFLICT (list_id, word) DO UPDATE SET score = EXCLUDED.score, notes = EXCLUDED.notes`,
+		listID, words, scores, notes)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE word_lists SET updated_at = NOW() WHERE id = $1`, listID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (m WordListModel) SetWord(ctx context.Context, listID int, entry wordlist.Entry) error {
+	return m.AddWords(ctx, listID, []wordlist.Entry{entry}, false)
+}
+
+func (m WordListModel) DeleteWord(ctx context.Context, listID int, word string) error {
+	query := `
+		DELETE FROM words
+		WHERE list_id = $1 AND word = $2`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.Exec(ctx, query, listID, word)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Lookup finds the words matching a pattern in the house list and the user's
+// own lists, best scores first. A word in one of the user's lists overrides
+// the same word in the house list, so constructors can rescore or bury house
+// entries they don't like.
+func (m WordListModel) Lookup(ctx context.Context, userID int, lookup WordLookup, filters Filters) ([]*Word, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), merged.word, merged.score, merged.notes, merged.list_id
+		FROM (
+			SELECT DISTINCT ON (w.word) w.word, w.score, w.notes, w.list_id
+			FROM words w
+			INNER JOIN word_lists l ON w.list_id = l.id
+			WHERE (l.owner_id IS NULL OR l.owner_id = $1)
+			AND (l.id = $2 OR $2 = 0)
+			AND length(w.word) = $3
+			AND w.word LIKE $4
+			ORDER BY w.word, l.owner_id NULLS LAST, l.id DESC
+		) merged
+		WHERE merged.score >= $5
+		ORDER BY merged.score DESC, merged.word
+		LIMIT $6 OFFSET $7`
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	args := []any{
+		userID,
+		lookup.ListID,
+		len(lookup.Pattern),
+		wordlist.LikePattern(lookup.Pattern),
+		lookup.MinScore,
+		filters.limit(),
+		filters.offset(),
+	}
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	words := []*Word{}
+	totalRecords := 0
+	for rows.Next() {
+		var word Word
+		err := rows.Scan(&totalRecords, &word.Word, &word.Score, &word.Notes, &word.ListID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		words = append(words, &word)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return words, metadata, nil
+}
+
+// GetForFill returns every word of one of the given lengths scoring at least
+// minScore from the house list and the user's own lists, or from a single
+// list when listID is set, with the user's scores overriding the house list's.
+func (m WordListModel) GetForFill(ctx context.Context, userID, listID, minScore int, lengths []int) ([]wordlist.Entry, error) {
+	query := `
+		SELECT merged.word, merged.score
+		FROM (
+			SELECT DISTINCT ON (w.word) w.word, w.score
+			FROM words w
+			INNER JOIN word_lists l ON w.list_id = l.id
+			WHERE (l.owner_id IS NULL OR l.owner_id = $1)
+			AND (l.id = $2 OR $2 = 0)
+			AND length(w.word) = ANY($3)
+			ORDER BY w.word, l.owner_id NULLS LAST, l.id DESC
+		) merged
+		WHERE merged.score >= $4`
+	ctx, cancel := bulkContext(ctx, m.BulkTimeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, userID, listID, nonNil(lengths), minScore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []wordlist.Entry
+	for rows.Next() {
+		var entry wordlist.Entry
+		err := rows.Scan(&entry.Word, &entry.Score)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetEntries returns every word in a list in alphabetical order.
+func (m WordListModel) GetEntries(ctx context.Context, listID int) ([]wordlist.Entry, error) {
+	query := `
+		SELECT word, score, notes
+		FROM words
+		WHERE list_id = $1
+		ORDER BY word`
+	ctx, cancel := bulkContext(ctx, m.BulkTimeout)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, query, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []wordlist.Entry{}
+	for rows.Next() {
+		var entry wordlist.Entry
+		err := rows.Scan(&entry.Word, &entry.Score, &entry.Notes)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
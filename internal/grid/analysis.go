@@ -0,0 +1,157 @@
+package grid
+
+import (
+	"slices"
+	"strings"
+)
+
+type Square struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// Analysis holds the construction metrics editors check before publishing.
+type Analysis struct {
+	WordCount          int            `json:"word_count"`
+	BlockCount         int            `json:"block_count"`
+	BlankSquares       int            `json:"blank_squares"`
+	AverageWordLength  float64        `json:"average_word_length"`
+	RotationalSymmetry bool           `json:"rotational_symmetry"`
+	MirrorSymmetry     bool           `json:"mirror_symmetry"`
+	UncheckedSquares   []Square       `json:"unchecked_squares"`
+	Regions            int            `json:"regions"`
+	IsolatedSquares    []Square       `json:"isolated_squares"`
+	DuplicateAnswers   []string       `json:"duplicate_answers"`
+	LetterCounts       map[string]int `json:"letter_counts"`
+	Pangram            bool           `json:"pangram"`
+	MissingLetters     []string       `json:"missing_letters"`
+}
+
+// Analyze measures a grid. Symmetry only looks at the pattern of blocks, not
+// the letters. An unchecked square is a white square that belongs to just one
+// entry. Regions counts the groups of white squares connected across or down;
+// squares outside the largest group are reported as isolated.
+func Analyze(g *Grid) Analysis {
+	a := Analysis{
+		RotationalSymmetry: true,
+		MirrorSymmetry:     true,
+		UncheckedSquares:   []Square{},
+		IsolatedSquares:    []Square{},
+		DuplicateAnswers:   []string{},
+		LetterCounts:       make(map[string]int),
+		MissingLetters:     []string{},
+	}
+
+	for r := 0; r < g.Height; r++ {
+		for c := 0; c < g.Width; c++ {
+			cell := g.At(r, c)
+			switch {
+			case cell == Block:
+				a.BlockCount++
+			case cell == Blank:
+				a.BlankSquares++
+			default:
+				a.LetterCounts[string(cell)]++
+			}
+			isBlock := cell == Block
+			if isBlock != (g.At(g.Height-1-r, g.Width-1-c) == Block) {
+				a.RotationalSymmetry = false
+			}
+			if isBlock != (g.At(r, g.Width-1-c) == Block) {
+				a.MirrorSymmetry = false
+			}
+		}
+	}
+
+	entries := g.Entries()
+	a.WordCount = len(entries)
+	checks := make([]int, len(g.Cells))
+	totalLength := 0
+	seen := make(map[string]int)
+	for _, e := range entries {
+		totalLength += e.Length
+		for i := 0; i < e.Length; i++ {
+			r, c := e.Cell(i)
+			checks[r*g.Width+c]++
+		}
+		word := g.Word(e)
+		if strings.IndexByte(word, Blank) == -1 {
+			seen[word]++
+			if seen[word] == 2 {
+				a.DuplicateAnswers = append(a.DuplicateAnswers, word)
+			}
+		}
+	}
+	if a.WordCount > 0 {
+		a.AverageWordLength = float64(totalLength) / float64(a.WordCount)
+	}
+	for r := 0; r < g.Height; r++ {
+		for c := 0; c < g.Width; c++ {
+			if g.At(r, c) != Block && checks[r*g.Width+c] < 2 {
+				a.UncheckedSquares = append(a.UncheckedSquares, Square{r, c})
+			}
+		}
+	}
+
+	regions := g.regions()
+	a.Regions = len(regions)
+	if len(regions) > 1 {
+		largest := 0
+		for i, region := range regions {
+			if len(region) > len(regions[largest]) {
+				largest = i
+			}
+		}
+		for i, region := range regions {
+			if i != largest {
+				a.IsolatedSquares = append(a.IsolatedSquares, region...)
+			}
+		}
+		slices.SortFunc(a.IsolatedSquares, func(x, y Square) int {
+			if x.Row != y.Row {
+				return x.Row - y.Row
+			}
+			return x.Col - y.Col
+		})
+	}
+
+	for l := 'A'; l <= 'Z'; l++ {
+		if a.LetterCounts[string(l)] == 0 {
+			a.MissingLetters = append(a.MissingLetters, string(l))
+		}
+	}
+	a.Pangram = len(a.MissingLetters) == 0
+	return a
+}
+
+// regions groups the white squares into sets connected across or down.
+func (g *Grid) regions() [][]Square {
+	visited := make([]bool, len(g.Cells))
+	var regions [][]Square
+	for start := range g.Cells {
+		if visited[start] || g.Cells[start] == Block {
+			continue
+		}
+		var region []Square
+		stack := []int{start}
+		visited[start] = true
+		for len(stack) > 0 {
+			cell := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			r, c := cell/g.Width, cell%g.Width
+			region = append(region, Square{r, c})
+			for _, n := range [][2]int{{r - 1, c}, {r + 1, c}, {r, c - 1}, {r, c + 1}} {
+				if !g.IsWhite(n[0], n[1]) {
+					continue
+				}
+				i := n[0]*g.Width + n[1]
+				if !visited[i] {
+					visited[i] = true
+					stack = append(stack, i)
+				}
+			}
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
@@ -0,0 +1,109 @@
+package grid
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lettersExcept returns the letters A to Z that aren't in present.
+func lettersExcept(present string) []string {
+	missing := []string{}
+	for l := 'A'; l <= 'Z'; l++ {
+		if !strings.ContainsRune(present, l) {
+			missing = append(missing, string(l))
+		}
+	}
+	return missing
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []string
+		want Analysis
+	}{
+		{
+			name: "Symmetric with unchecked squares",
+			rows: []string{"CAT", "O#O", "WOE"},
+			want: Analysis{
+				WordCount:          4,
+				BlockCount:         1,
+				AverageWordLength:  3,
+				RotationalSymmetry: true,
+				MirrorSymmetry:     true,
+				UncheckedSquares:   []Square{{0, 1}, {1, 0}, {1, 2}, {2, 1}},
+				Regions:            1,
+				IsolatedSquares:    []Square{},
+				DuplicateAnswers:   []string{},
+				LetterCounts:       map[string]int{"C": 1, "A": 1, "T": 1, "O": 3, "W": 1, "E": 1},
+				MissingLetters:     lettersExcept("CATOWE"),
+			},
+		},
+		{
+			name: "Duplicate answers",
+			rows: []string{"CAT", "A#A", "TAT"},
+			want: Analysis{
+				WordCount:          4,
+				BlockCount:         1,
+				AverageWordLength:  3,
+				RotationalSymmetry: true,
+				MirrorSymmetry:     true,
+				UncheckedSquares:   []Square{{0, 1}, {1, 0}, {1, 2}, {2, 1}},
+				Regions:            1,
+				IsolatedSquares:    []Square{},
+				DuplicateAnswers:   []string{"CAT", "TAT"},
+				LetterCounts:       map[string]int{"C": 1, "A": 4, "T": 3},
+				MissingLetters:     lettersExcept("CAT"),
+			},
+		},
+		{
+			name: "Asymmetric with an isolated region",
+			rows: []string{"..#.", "..#.", "..##"},
+			want: Analysis{
+				WordCount:         6,
+				BlockCount:        4,
+				BlankSquares:      8,
+				AverageWordLength: 14.0 / 6,
+				UncheckedSquares:  []Square{{0, 3}, {1, 3}},
+				Regions:           2,
+				IsolatedSquares:   []Square{{0, 3}, {1, 3}},
+				DuplicateAnswers:  []string{},
+				LetterCounts:      map[string]int{},
+				MissingLetters:    lettersExcept(""),
+			},
+		},
+		{
+			name: "Pangram",
+			rows: []string{"ABCDEFGHIJKLM", "NOPQRSTUVWXYZ"},
+			want: Analysis{
+				WordCount:          15,
+				AverageWordLength:  52.0 / 15,
+				RotationalSymmetry: true,
+				MirrorSymmetry:     true,
+				UncheckedSquares:   []Square{},
+				Regions:            1,
+				IsolatedSquares:    []Square{},
+				DuplicateAnswers:   []string{},
+				LetterCounts:       map[string]int{},
+				Pangram:            true,
+				MissingLetters:     []string{},
+			},
+		},
+	}
+	for l := 'A'; l <= 'Z'; l++ {
+		tests[3].want.LetterCounts[string(l)] = 1
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Analyze(g); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
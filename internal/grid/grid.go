@@ -0,0 +1,221 @@
+package grid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Block marks a black square.
+	Block byte = '#'
+	// Blank marks a white square whose letter isn't known yet.
+	Blank byte = '.'
+)
+
+type Direction string
+
+const (
+	Across Direction = "across"
+	Down   Direction = "down"
+)
+
+// MaxSize is the most squares a grid may have along either side, which keeps
+// the memory needed to render or fill one small.
+const MaxSize = 50
+
+var (
+	ErrConflict = errors.New("crossing entries disagree on a letter")
+	ErrSize     = fmt.Errorf("grid must be between 1 and %d squares wide and high", MaxSize)
+)
+
+// ValidSize reports whether a grid of the given size is within MaxSize.
+func ValidSize(width, height int) bool {
+	return width > 0 && width <= MaxSize && height > 0 && height <= MaxSize
+}
+
+// Entry is a run of white squares read across or down from its first square.
+type Entry struct {
+	Direction Direction `json:"direction"`
+	Number    int       `json:"number"`
+	Row       int       `json:"row"`
+	Col       int       `json:"col"`
+	Length    int       `json:"length"`
+}
+
+// Cell returns the row and column of the i'th square of the entry.
+func (e Entry) Cell(i int) (row, col int) {
+	if e.Direction == Across {
+		return e.Row, e.Col + i
+	}
+	return e.Row + i, e.Col
+}
+
+// Grid is a rectangle of squares stored row by row. Each square holds Block,
+// Blank or an upper case letter.
+type Grid struct {
+	Width  int
+	Height int
+	Cells  []byte
+}
+
+// New returns a grid made entirely of blocks; entries are carved out of it by
+// placing words.
+func New(width, height int) *Grid {
+	cells := make([]byte, width*height)
+	for i := range cells {
+		cells[i] = Block
+	}
+	return &Grid{Width: width, Height: height, Cells: cells}
+}
+
+// Parse builds a grid from rows of text using # for blocks, a letter for a
+// filled square and any of . ? _ or a space for an empty one.
+func Parse(rows []string) (*Grid, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("grid must have at least one row")
+	}
+	g := New(len(rows[0]), len(rows))
+	for r, row := range rows {
+		if len(row) != g.Width {
+			return nil, fmt.Errorf("row %d must be %d squares wide", r+1, g.Width)
+		}
+		for c := 0; c < len(row); c++ {
+			cell, ok := normalizeCell(row[c])
+			if !ok {
+				return nil, fmt.Errorf("row %d contains invalid square %q", r+1, row[c])
+			}
+			g.Set(r, c, cell)
+		}
+	}
+	return g, nil
+}
+
+func normalizeCell(b byte) (byte, bool) {
+	switch {
+	case b == Block:
+		return Block, true
+	case b == Blank || b == '?' || b == '_' || b == ' ':
+		return Blank, true
+	case b >= 'a' && b <= 'z':
+		return b - 'a' + 'A', true
+	case b >= 'A' && b <= 'Z':
+		return b, true
+	}
+	return 0, false
+}
+
+// Clone returns a deep copy of the grid.
+func (g *Grid) Clone() *Grid {
+	cells := make([]byte, len(g.Cells))
+	copy(cells, g.Cells)
+	return &Grid{Width: g.Width, Height: g.Height, Cells: cells}
+}
+
+func (g *Grid) InBounds(row, col int) bool {
+	return row >= 0 && row < g.Height && col >= 0 && col < g.Width
+}
+
+func (g *Grid) At(row, col int) byte {
+	return g.Cells[row*g.Width+col]
+}
+
+func (g *Grid) Set(row, col int, cell byte) {
+	g.Cells[row*g.Width+col] = cell
+}
+
+// IsWhite reports whether the square is in bounds and not a block.
+func (g *Grid) IsWhite(row, col int) bool {
+	return g.InBounds(row, col) && g.At(row, col) != Block
+}
+
+// Place writes an answer into the grid starting at row, col. Letters outside
+// A-Z are treated as unknown. It fails if the answer runs off the grid or a
+// letter disagrees with one already placed by a crossing entry.
+func (g *Grid) Place(direction Direction, row, col int, answer string) error {
+	e := Entry{Direction: direction, Row: row, Col: col, Length: len(answer)}
+	for i := 0; i < e.Length; i++ {
+		r, c := e.Cell(i)
+		if !g.InBounds(r, c) {
+			return fmt.Errorf("%s entry at row %d, column %d runs off the grid", direction, row, col)
+		}
+		cell, ok := normalizeCell(answer[i])
+		if !ok || cell == Block {
+			cell = Blank
+		}
+		current := g.At(r, c)
+		switch {
+		case current == Block || current == Blank:
+			g.Set(r, c, cell)
+		case cell != Blank && cell != current:
+			return fmt.Errorf("%w at row %d, column %d", ErrConflict, r, c)
+		}
+	}
+	return nil
+}
+
+// Word returns the letters of an entry, with Blank for unknown squares.
+func (g *Grid) Word(e Entry) string {
+	word := make([]byte, e.Length)
+	for i := range word {
+		word[i] = g.At(e.Cell(i))
+	}
+	return string(word)
+}
+
+// Write fills an entry with a word of the same length.
+func (g *Grid) Write(e Entry, word string) {
+	for i := 0; i < e.Length; i++ {
+		r, c := e.Cell(i)
+		g.Set(r, c, word[i])
+	}
+}
+
+// Entries numbers the grid the conventional way, scanning rows top to bottom
+// and giving a number to every square that starts an across or down run of
+// two or more white squares. Across entries are returned before down ones.
+func (g *Grid) Entries() []Entry {
+	var across, down []Entry
+	number := 0
+	for r := 0; r < g.Height; r++ {
+		for c := 0; c < g.Width; c++ {
+			if !g.IsWhite(r, c) {
+				continue
+			}
+			startsAcross := !g.IsWhite(r, c-1) && g.IsWhite(r, c+1)
+			startsDown := !g.IsWhite(r-1, c) && g.IsWhite(r+1, c)
+			if !startsAcross && !startsDown {
+				continue
+			}
+			number++
+			if startsAcross {
+				e := Entry{Direction: Across, Number: number, Row: r, Col: c}
+				for g.IsWhite(r, c+e.Length) {
+					e.Length++
+				}
+				across = append(across, e)
+			}
+			if startsDown {
+				e := Entry{Direction: Down, Number: number, Row: r, Col: c}
+				for g.IsWhite(r+e.Length, c) {
+					e.Length++
+				}
+				down = append(down, e)
+			}
+		}
+	}
+	return append(across, down...)
+}
+
+// Rows returns the grid as one string per row.
+func (g *Grid) Rows() []string {
+	rows := make([]string, g.Height)
+	for r := range rows {
+		rows[r] = string(g.Cells[r*g.Width : (r+1)*g.Width])
+	}
+	return rows
+}
+
+func (g *Grid) String() string {
+	return strings.Join(g.Rows(), "\n")
+}
@@ -0,0 +1,149 @@
+package grid
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     []string
+		wantRows []string
+		wantErr  bool
+	}{
+		{
+			name:     "Letters and blocks",
+			rows:     []string{"CAT", "O#O", "WOE"},
+			wantRows: []string{"CAT", "O#O", "WOE"},
+		},
+		{
+			name:     "Lower case and empty squares",
+			rows:     []string{"c?t", "._ ", "#.#"},
+			wantRows: []string{"C.T", "...", "#.#"},
+		},
+		{
+			name:    "No rows",
+			rows:    []string{},
+			wantErr: true,
+		},
+		{
+			name:    "Ragged rows",
+			rows:    []string{"CAT", "CA"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid square",
+			rows:    []string{"CAT", "C4T"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.rows)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error; want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.Width != len(tt.wantRows[0]) || g.Height != len(tt.wantRows) {
+				t.Errorf("got %dx%d; want %dx%d", g.Width, g.Height, len(tt.wantRows[0]), len(tt.wantRows))
+			}
+			if got := g.Rows(); !reflect.DeepEqual(got, tt.wantRows) {
+				t.Errorf("got rows %q; want %q", got, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []string
+		want []Entry
+	}{
+		{
+			name: "Open grid",
+			rows: []string{"...", "...", "..."},
+			want: []Entry{
+				{Across, 1, 0, 0, 3},
+				{Across, 4, 1, 0, 3},
+				{Across, 5, 2, 0, 3},
+				{Down, 1, 0, 0, 3},
+				{Down, 2, 0, 1, 3},
+				{Down, 3, 0, 2, 3},
+			},
+		},
+		{
+			name: "Center block",
+			rows: []string{"...", ".#.", "..."},
+			want: []Entry{
+				{Across, 1, 0, 0, 3},
+				{Across, 3, 2, 0, 3},
+				{Down, 1, 0, 0, 3},
+				{Down, 2, 0, 2, 3},
+			},
+		},
+		{
+			name: "Single squares aren't entries",
+			rows: []string{".#.", "#.#", ".#."},
+			want: nil,
+		},
+		{
+			name: "Entries end at blocks",
+			rows: []string{"..#..", "....."},
+			want: []Entry{
+				{Across, 1, 0, 0, 2},
+				{Across, 3, 0, 3, 2},
+				{Across, 5, 1, 0, 5},
+				{Down, 1, 0, 0, 2},
+				{Down, 2, 0, 1, 2},
+				{Down, 3, 0, 3, 2},
+				{Down, 4, 0, 4, 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := g.Entries(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got entries %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlace(t *testing.T) {
+	g := New(3, 3)
+	err := g.Place(Across, 0, 0, "CAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.Place(Down, 0, 0, "C-W")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"CAT", ".##", "W##"}
+	if got := g.Rows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got rows %q; want %q", got, want)
+	}
+
+	err = g.Place(Down, 0, 2, "DOG")
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("placing a conflicting answer got %v; want %v", err, ErrConflict)
+	}
+	err = g.Place(Across, 2, 1, "OWL")
+	if err == nil {
+		t.Error("placing an answer off the grid got no error")
+	}
+}
@@ -0,0 +1,75 @@
+package puzzlefile
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+)
+
+const (
+	FormatNDJSON = "ndjson"
+	FormatIPUZ   = "ipuz"
+)
+
+var ExportFormats = []string{FormatNDJSON, FormatIPUZ}
+
+// ExportWriter writes a stream of puzzles either as newline delimited JSON,
+// one puzzle per line in the form the API returns them, or as a zip archive
+// holding an ipuz file for each puzzle. Close must be called to finish the
+// archive.
+type ExportWriter struct {
+	format string
+	enc    *json.Encoder
+	zw     *zip.Writer
+}
+
+func NewExportWriter(w io.Writer, format string) (*ExportWriter, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ExportWriter{format: format, enc: json.NewEncoder(w)}, nil
+	case FormatIPUZ:
+		return &ExportWriter{format: format, zw: zip.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// ContentType returns the media type of the export.
+func (e *ExportWriter) ContentType() string {
+	if e.format == FormatIPUZ {
+		return "application/zip"
+	}
+	return "application/x-ndjson"
+}
+
+// Extension returns the file extension for the export.
+func (e *ExportWriter) Extension() string {
+	if e.format == FormatIPUZ {
+		return ".zip"
+	}
+	return ".ndjson"
+}
+
+func (e *ExportWriter) Write(puzzle *data.Puzzle) error {
+	if e.enc != nil {
+		return e.enc.Encode(puzzle)
+	}
+	fw, err := e.zw.Create(fmt.Sprintf("puzzle-%d.ipuz", puzzle.ID))
+	if err != nil {
+		return err
+	}
+	err = WriteIPUZ(fw, puzzle)
+	if err != nil {
+		return fmt.Errorf("puzzle %d: %w", puzzle.ID, err)
+	}
+	return nil
+}
+
+func (e *ExportWriter) Close() error {
+	if e.zw != nil {
+		return e.zw.Close()
+	}
+	return nil
+}
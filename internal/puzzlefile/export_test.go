@@ -0,0 +1,122 @@
+package puzzlefile
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+)
+
+func TestIPUZRoundTrip(t *testing.T) {
+	want := testPuzzle()
+	want.Difficulty = 2
+
+	var buf bytes.Buffer
+	err := WriteIPUZ(&buf, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isIPUZ(buf.Bytes()) {
+		t.Fatal("written file isn't recognised as ipuz")
+	}
+	got, err := ReadIPUZ(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPuzzle(t, got, want)
+}
+
+func TestExportWriter(t *testing.T) {
+	first, second := testPuzzle(), testPuzzle()
+	first.ID, second.ID = 1, 2
+	second.Title = "Second"
+
+	t.Run("ndjson", func(t *testing.T) {
+		var buf bytes.Buffer
+		ew, err := NewExportWriter(&buf, FormatNDJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []*data.Puzzle{first, second} {
+			err = ew.Write(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = ew.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var titles []string
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var p data.Puzzle
+			err := json.Unmarshal(scanner.Bytes(), &p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			titles = append(titles, p.Title)
+		}
+		if len(titles) != 2 || titles[0] != first.Title || titles[1] != second.Title {
+			t.Errorf("got titles %q; want %q and %q", titles, first.Title, second.Title)
+		}
+	})
+
+	t.Run("ipuz", func(t *testing.T) {
+		var buf bytes.Buffer
+		ew, err := NewExportWriter(&buf, FormatIPUZ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []*data.Puzzle{first, second} {
+			err = ew.Write(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = ew.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]*data.Puzzle{"puzzle-1.ipuz": first, "puzzle-2.ipuz": second}
+		if len(zr.File) != len(want) {
+			t.Fatalf("got %d files; want %d", len(zr.File), len(want))
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Read(f.Name, b)
+			if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+			if want[f.Name] == nil {
+				t.Fatalf("unexpected file %s", f.Name)
+			}
+			checkPuzzle(t, got, want[f.Name])
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		_, err := NewExportWriter(io.Discard, "csv")
+		if err == nil {
+			t.Error("got no error; want one")
+		}
+	})
+}
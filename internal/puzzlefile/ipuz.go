@@ -0,0 +1,365 @@
+package puzzlefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+// ipuz is the subset of the ipuz crossword format (http://ipuz.org) we read
+// and write. Cells and clues come in several shapes, so they're decoded by
+// hand.
+type ipuz struct {
+	Version    string                       `json:"version"`
+	Kind       []string                     `json:"kind"`
+	Title      string                       `json:"title,omitempty"`
+	Author     string                       `json:"author,omitempty"`
+	Copyright  string                       `json:"copyright,omitempty"`
+	Intro      string                       `json:"intro,omitempty"`
+	Notes      string                       `json:"notes,omitempty"`
+	Difficulty string                       `json:"difficulty,omitempty"`
+	Dimensions ipuzDimensions               `json:"dimensions"`
+	Block      string                       `json:"block,omitempty"`
+	Puzzle     [][]json.RawMessage          `json:"puzzle"`
+	Solution   [][]json.RawMessage          `json:"solution"`
+	Clues      map[string][]json.RawMessage `json:"clues"`
+}
+
+type ipuzDimensions struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func isIPUZ(b []byte) bool {
+	var header struct {
+		Version string `json:"version"`
+	}
+	return json.Unmarshal(b, &header) == nil && strings.Contains(header.Version, "ipuz.org")
+}
+
+// ReadIPUZ parses an ipuz crossword. Rebus squares aren't supported.
+func ReadIPUZ(b []byte) (*data.Puzzle, error) {
+	var f ipuz
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ipuz: %w", err)
+	}
+
+	crossword := false
+	for _, kind := range f.Kind {
+		crossword = crossword || strings.HasPrefix(kind, "http://ipuz.org/crossword")
+	}
+	if !crossword {
+		return nil, errors.New("only ipuz crosswords are supported")
+	}
+
+	width, height := f.Dimensions.Width, f.Dimensions.Height
+	if !grid.ValidSize(width, height) {
+		return nil, grid.ErrSize
+	}
+	if len(f.Puzzle) != height {
+		return nil, errors.New("ipuz dimensions don't match the puzzle grid")
+	}
+	block := f.Block
+	if block == "" {
+		block = "#"
+	}
+
+	g := grid.New(width, height)
+	positions := make(map[int]grid.Square)
+	var circles []grid.Square
+	for r, row := range f.Puzzle {
+		if len(row) != width {
+			return nil, fmt.Errorf("ipuz puzzle row %d must be %d squares wide", r+1, width)
+		}
+		for c, raw := range row {
+			cell, err := readIPUZCell(raw, block)
+			if err != nil {
+				return nil, fmt.Errorf("ipuz puzzle row %d: %w", r+1, err)
+			}
+			if cell.block {
+				continue
+			}
+			g.Set(r, c, grid.Blank)
+			if cell.number > 0 {
+				positions[cell.number] = grid.Square{Row: r, Col: c}
+			}
+			if cell.circled {
+				circles = append(circles, grid.Square{Row: r, Col: c})
+			}
+		}
+	}
+
+	if len(f.Solution) != height {
+		return nil, ErrNoSolution
+	}
+	for r, row := range f.Solution {
+		if len(row) != width {
+			return nil, fmt.Errorf("ipuz solution row %d must be %d squares wide", r+1, width)
+		}
+		for c, raw := range row {
+			if !g.IsWhite(r, c) {
+				continue
+			}
+			letter, err := readIPUZLetter(raw, block)
+			if err != nil {
+				return nil, fmt.Errorf("ipuz solution row %d: %w", r+1, err)
+			}
+			g.Set(r, c, letter)
+		}
+	}
+	if len(positions) == 0 {
+		positions = standardPositions(g)
+	}
+
+	clues := map[grid.Direction]map[int]string{
+		grid.Across: make(map[int]string),
+		grid.Down:   make(map[int]string),
+	}
+	for key, list := range f.Clues {
+		direction := grid.Direction(strings.ToLower(strings.SplitN(key, ":", 2)[0]))
+		if clues[direction] == nil {
+			continue
+		}
+		for _, raw := range list {
+			number, text, err := readIPUZClue(raw)
+			if err != nil {
+				return nil, fmt.Errorf("ipuz %s clues: %w", direction, err)
+			}
+			clues[direction][number] = text
+		}
+	}
+
+	content, err := contentFromGrid(g, positions, clues[grid.Across], clues[grid.Down])
+	if err != nil {
+		return nil, err
+	}
+	content.Circles = circles
+
+	puzzle := &data.Puzzle{
+		Title:       f.Title,
+		Description: f.Intro,
+		Width:       width,
+		Height:      height,
+		Content:     content,
+		Notes:       f.Notes,
+	}
+	if difficulty, err := strconv.Atoi(f.Difficulty); err == nil && data.ValidDifficulty(difficulty) {
+		puzzle.Difficulty = difficulty
+	}
+	return puzzle, nil
+}
+
+type ipuzCell struct {
+	number  int
+	block   bool
+	circled bool
+}
+
+// readIPUZCell decodes a square of the puzzle grid: a clue number, the block
+// string, null for an omitted square, or an object with a cell and a style.
+func readIPUZCell(raw json.RawMessage, block string) (ipuzCell, error) {
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) == 0 || string(raw) == "null":
+		return ipuzCell{block: true}, nil
+	case raw[0] == '{':
+		var obj struct {
+			Cell  json.RawMessage `json:"cell"`
+			Style json.RawMessage `json:"style"`
+		}
+		err := json.Unmarshal(raw, &obj)
+		if err != nil {
+			return ipuzCell{}, err
+		}
+		cell, err := readIPUZCell(obj.Cell, block)
+		if err != nil || len(obj.Cell) == 0 {
+			cell = ipuzCell{}
+		}
+		var style struct {
+			Shape string `json:"shapebg"`
+		}
+		if json.Unmarshal(obj.Style, &style) == nil && style.Shape == "circle" {
+			cell.circled = true
+		}
+		return cell, nil
+	case raw[0] == '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		if err != nil {
+			return ipuzCell{}, err
+		}
+		if s == block {
+			return ipuzCell{block: true}, nil
+		}
+		number, _ := strconv.Atoi(s)
+		return ipuzCell{number: number}, nil
+	}
+	var number int
+	err := json.Unmarshal(raw, &number)
+	if err != nil {
+		return ipuzCell{}, fmt.Errorf("invalid square %s", raw)
+	}
+	return ipuzCell{number: number}, nil
+}
+
+// readIPUZLetter decodes a square of the solution grid: a letter, or an
+// object with a value.
+func readIPUZLetter(raw json.RawMessage, block string) (byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return grid.Blank, nil
+	}
+	var s string
+	if raw[0] == '{' {
+		var obj struct {
+			Value string `json:"value"`
+		}
+		err := json.Unmarshal(raw, &obj)
+		if err != nil {
+			return 0, err
+		}
+		s = obj.Value
+	} else if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, fmt.Errorf("invalid square %s", raw)
+	}
+
+	switch {
+	case s == "" || s == block:
+		return grid.Blank, nil
+	case utf8.RuneCountInString(s) > 1:
+		return 0, errors.New("rebus squares aren't supported")
+	}
+	letter := strings.ToUpper(s)[0]
+	if letter < 'A' || letter > 'Z' {
+		return 0, fmt.Errorf("invalid letter %q", s)
+	}
+	return letter, nil
+}
+
+// readIPUZClue decodes a clue given as [number, text] or as an object with a
+// number and a clue.
+func readIPUZClue(raw json.RawMessage) (int, string, error) {
+	var (
+		number json.RawMessage
+		text   string
+	)
+	var pair []json.RawMessage
+	if err := json.Unmarshal(raw, &pair); err == nil {
+		if len(pair) < 2 || json.Unmarshal(pair[1], &text) != nil {
+			return 0, "", fmt.Errorf("invalid clue %s", raw)
+		}
+		number = pair[0]
+	} else {
+		var obj struct {
+			Number json.RawMessage `json:"number"`
+			Clue   string          `json:"clue"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return 0, "", fmt.Errorf("invalid clue %s", raw)
+		}
+		number, text = obj.Number, obj.Clue
+	}
+
+	var n int
+	if err := json.Unmarshal(number, &n); err != nil {
+		var s string
+		if json.Unmarshal(number, &s) != nil {
+			return 0, "", fmt.Errorf("invalid clue number %s", number)
+		}
+		n, err = strconv.Atoi(s)
+		if err != nil {
+			return 0, "", fmt.Errorf("unsupported clue number %q", s)
+		}
+	}
+	return n, text, nil
+}
+
+// WriteIPUZ writes a puzzle as an ipuz crossword. Squares are numbered from
+// the positions of the puzzle's clues.
+func WriteIPUZ(w io.Writer, puzzle *data.Puzzle) error {
+	g, err := puzzle.Content.Grid(puzzle.Width, puzzle.Height)
+	if err != nil {
+		return err
+	}
+
+	numbers := make(map[grid.Square]int)
+	clues := make(map[string][]json.RawMessage)
+	for _, section := range []struct {
+		name  string
+		clues map[string]data.ClueData
+	}{{"Across", puzzle.Content.Across}, {"Down", puzzle.Content.Down}} {
+		keys := make([]int, 0, len(section.clues))
+		for key, clue := range section.clues {
+			number, err := strconv.Atoi(key)
+			if err != nil {
+				continue
+			}
+			numbers[grid.Square{Row: clue.Row, Col: clue.Col}] = number
+			keys = append(keys, number)
+		}
+		slices.Sort(keys)
+		for _, number := range keys {
+			clues[section.name] = append(clues[section.name], rawJSON([]any{number, section.clues[strconv.Itoa(number)].Clue}))
+		}
+	}
+	circled := make(map[grid.Square]bool)
+	for _, sq := range puzzle.Content.Circles {
+		circled[sq] = true
+	}
+
+	f := ipuz{
+		Version:    "http://ipuz.org/v2",
+		Kind:       []string{"http://ipuz.org/crossword#1"},
+		Title:      puzzle.Title,
+		Author:     puzzle.Author.DisplayName,
+		Intro:      puzzle.Description,
+		Notes:      puzzle.Notes,
+		Dimensions: ipuzDimensions{Width: puzzle.Width, Height: puzzle.Height},
+		Puzzle:     make([][]json.RawMessage, puzzle.Height),
+		Solution:   make([][]json.RawMessage, puzzle.Height),
+		Clues:      clues,
+	}
+	if puzzle.Difficulty != 0 {
+		f.Difficulty = strconv.Itoa(puzzle.Difficulty)
+	}
+	for r := 0; r < g.Height; r++ {
+		f.Puzzle[r] = make([]json.RawMessage, g.Width)
+		f.Solution[r] = make([]json.RawMessage, g.Width)
+		for c := 0; c < g.Width; c++ {
+			sq := grid.Square{Row: r, Col: c}
+			cell := g.At(r, c)
+			switch {
+			case cell == grid.Block:
+				f.Puzzle[r][c] = rawJSON("#")
+				f.Solution[r][c] = rawJSON("#")
+				continue
+			case cell == grid.Blank:
+				f.Solution[r][c] = rawJSON(nil)
+			default:
+				f.Solution[r][c] = rawJSON(string(cell))
+			}
+			if circled[sq] {
+				f.Puzzle[r][c] = rawJSON(map[string]any{"cell": numbers[sq], "style": map[string]string{"shapebg": "circle"}})
+			} else {
+				f.Puzzle[r][c] = rawJSON(numbers[sq])
+			}
+		}
+	}
+
+	return json.NewEncoder(w).Encode(f)
+}
+
+func rawJSON(v any) json.RawMessage {
+	b, _ := json.Marshal(v)
+	return b
+}
@@ -0,0 +1,174 @@
+package puzzlefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+// Offsets into the header of an Across Lite .puz file.
+const (
+	puzMagicOffset     = 0x02
+	puzWidthOffset     = 0x2C
+	puzHeightOffset    = 0x2D
+	puzClueCountOffset = 0x2E
+	puzScrambledOffset = 0x32
+	puzHeaderSize      = 0x34
+	puzCircled         = 0x80
+)
+
+var (
+	puzMagic     = []byte("ACROSS&DOWN\x00")
+	errBadPUZ    = errors.New("invalid .puz file")
+	errScrambled = errors.New("scrambled .puz files aren't supported")
+)
+
+// ReadPUZ parses an Across Lite .puz file. Its strings are Latin-1. Circles
+// come from the GEXT section; puzzles with rebus squares are rejected.
+func ReadPUZ(b []byte) (*data.Puzzle, error) {
+	if len(b) < puzHeaderSize || !bytes.Equal(b[puzMagicOffset:puzMagicOffset+len(puzMagic)], puzMagic) {
+		return nil, errBadPUZ
+	}
+	if binary.LittleEndian.Uint16(b[puzScrambledOffset:]) != 0 {
+		return nil, errScrambled
+	}
+
+	width, height := int(b[puzWidthOffset]), int(b[puzHeightOffset])
+	clueCount := int(binary.LittleEndian.Uint16(b[puzClueCountOffset:]))
+	size := width * height
+	if width == 0 || height == 0 || len(b) < puzHeaderSize+2*size {
+		return nil, errBadPUZ
+	}
+	if !grid.ValidSize(width, height) {
+		return nil, grid.ErrSize
+	}
+
+	solution := b[puzHeaderSize : puzHeaderSize+size]
+	g := grid.New(width, height)
+	for i, cell := range solution {
+		switch {
+		case cell == '.' || cell == ':':
+			// blocks; the colon marks a diagramless block
+		case cell >= 'A' && cell <= 'Z':
+			g.Cells[i] = cell
+		case cell >= 'a' && cell <= 'z':
+			g.Cells[i] = cell - 'a' + 'A'
+		default:
+			g.Cells[i] = grid.Blank
+		}
+	}
+
+	rest := b[puzHeaderSize+2*size:]
+	next := func() (string, bool) {
+		end := bytes.IndexByte(rest, 0)
+		if end == -1 {
+			return "", false
+		}
+		s := latin1(rest[:end])
+		rest = rest[end+1:]
+		return s, true
+	}
+
+	var strs [3]string
+	for i := range strs {
+		s, ok := next()
+		if !ok {
+			return nil, errBadPUZ
+		}
+		strs[i] = s
+	}
+	title, copyright := strs[0], strs[2]
+
+	// the clues are stored in the order the numbered squares are read, with
+	// across before down for a square that starts both
+	across := make(map[int]string)
+	down := make(map[int]string)
+	entries := g.Entries()
+	var ordered []grid.Entry
+	for _, e := range entries {
+		if e.Direction == grid.Across {
+			ordered = append(ordered, e)
+		}
+	}
+	ordered = mergeByNumber(ordered, entries[len(ordered):])
+	if len(ordered) != clueCount {
+		return nil, errors.New(".puz clue count doesn't match the grid")
+	}
+	for _, e := range ordered {
+		clue, ok := next()
+		if !ok {
+			return nil, errBadPUZ
+		}
+		if e.Direction == grid.Across {
+			across[e.Number] = clue
+		} else {
+			down[e.Number] = clue
+		}
+	}
+	notes, _ := next()
+
+	content, err := contentFromGrid(g, standardPositions(g), across, down)
+	if err != nil {
+		return nil, err
+	}
+
+	// extra sections are a four letter name, a length, a checksum, the data
+	// and a terminating zero
+	for len(rest) >= 8 {
+		name := string(rest[:4])
+		length := int(binary.LittleEndian.Uint16(rest[4:]))
+		if len(rest) < 8+length+1 {
+			break
+		}
+		section := rest[8 : 8+length]
+		switch name {
+		case "GRBS":
+			return nil, errors.New("rebus squares aren't supported")
+		case "GEXT":
+			for i, flags := range section {
+				if flags&puzCircled != 0 && i < size {
+					content.Circles = append(content.Circles, grid.Square{Row: i / width, Col: i % width})
+				}
+			}
+		}
+		rest = rest[8+length+1:]
+	}
+
+	return &data.Puzzle{
+		Title:       title,
+		Description: copyright,
+		Width:       width,
+		Height:      height,
+		Content:     content,
+		Notes:       notes,
+	}, nil
+}
+
+// mergeByNumber interleaves across and down entries, each already in number
+// order, into a single list ordered by number with across first.
+func mergeByNumber(across, down []grid.Entry) []grid.Entry {
+	merged := make([]grid.Entry, 0, len(across)+len(down))
+	i, j := 0, 0
+	for i < len(across) || j < len(down) {
+		if j == len(down) || (i < len(across) && across[i].Number <= down[j].Number) {
+			merged = append(merged, across[i])
+			i++
+		} else {
+			merged = append(merged, down[j])
+			j++
+		}
+	}
+	return merged
+}
+
+func latin1(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
@@ -0,0 +1,143 @@
+package puzzlefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported file type")
+	ErrNoSolution  = errors.New("puzzle has no solution")
+)
+
+// Read parses a puzzle file in one of the formats we accept, going by the
+// file's extension. A .json file may hold either our own format or ipuz.
+// The puzzle that's returned has no author and hasn't been validated.
+func Read(name string, b []byte) (*data.Puzzle, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".ipuz":
+		return ReadIPUZ(b)
+	case ".puz":
+		return ReadPUZ(b)
+	case ".json":
+		if isIPUZ(b) {
+			return ReadIPUZ(b)
+		}
+		return ReadJSON(b)
+	}
+	return nil, ErrUnsupported
+}
+
+// File is a puzzle in our own JSON format, the same shape as the body
+// accepted when creating a puzzle through the API.
+type File struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	Content     data.PuzzleData `json:"content"`
+	Tags        []string        `json:"tags,omitempty"`
+	Theme       string          `json:"theme,omitempty"`
+	Notes       string          `json:"notes,omitempty"`
+	Difficulty  int             `json:"difficulty,omitempty"`
+}
+
+func NewFile(puzzle *data.Puzzle) File {
+	return File{
+		Title:       puzzle.Title,
+		Description: puzzle.Description,
+		Width:       puzzle.Width,
+		Height:      puzzle.Height,
+		Content:     puzzle.Content,
+		Tags:        puzzle.Tags,
+		Theme:       puzzle.Theme,
+		Notes:       puzzle.Notes,
+		Difficulty:  puzzle.Difficulty,
+	}
+}
+
+func ReadJSON(b []byte) (*data.Puzzle, error) {
+	var f File
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	// make sure the clues agree with each other and fit the grid, which
+	// refuses sizes beyond grid.MaxSize before allocating it
+	if f.Width > 0 && f.Height > 0 {
+		_, err = f.Content.Grid(f.Width, f.Height)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &data.Puzzle{
+		Title:       f.Title,
+		Description: f.Description,
+		Width:       f.Width,
+		Height:      f.Height,
+		Content:     f.Content,
+		Tags:        data.NormalizeTags(f.Tags),
+		Theme:       f.Theme,
+		Notes:       f.Notes,
+		Difficulty:  f.Difficulty,
+	}, nil
+}
+
+// contentFromGrid builds puzzle content from a solved grid and clues keyed by
+// number. Each clue's square comes from positions, and its answer is read
+// from the grid up to the next block or edge.
+func contentFromGrid(g *grid.Grid, positions map[int]grid.Square, across, down map[int]string) (data.PuzzleData, error) {
+	content := data.PuzzleData{
+		Across: make(map[string]data.ClueData, len(across)),
+		Down:   make(map[string]data.ClueData, len(down)),
+	}
+	add := func(direction grid.Direction, clues map[int]string, into map[string]data.ClueData) error {
+		for number, clue := range clues {
+			sq, ok := positions[number]
+			if !ok {
+				return fmt.Errorf("%s clue %d has no numbered square", direction, number)
+			}
+			e := grid.Entry{Direction: direction, Number: number, Row: sq.Row, Col: sq.Col}
+			for r, c := e.Cell(e.Length); g.IsWhite(r, c); r, c = e.Cell(e.Length) {
+				e.Length++
+			}
+			answer := g.Word(e)
+			if e.Length == 0 || strings.IndexByte(answer, grid.Blank) != -1 {
+				return ErrNoSolution
+			}
+			into[strconv.Itoa(number)] = data.ClueData{Row: sq.Row, Col: sq.Col, Clue: clue, Answer: answer}
+		}
+		return nil
+	}
+
+	err := add(grid.Across, across, content.Across)
+	if err != nil {
+		return data.PuzzleData{}, err
+	}
+	err = add(grid.Down, down, content.Down)
+	if err != nil {
+		return data.PuzzleData{}, err
+	}
+	return content, nil
+}
+
+// standardPositions numbers a grid the conventional way.
+func standardPositions(g *grid.Grid) map[int]grid.Square {
+	positions := make(map[int]grid.Square)
+	for _, e := range g.Entries() {
+		positions[e.Number] = grid.Square{Row: e.Row, Col: e.Col}
+	}
+	return positions
+}
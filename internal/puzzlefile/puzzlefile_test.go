@@ -0,0 +1,286 @@
+package puzzlefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ggetzie/badwords_be/internal/data"
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+// testPuzzle returns the puzzle the sample files below describe:
+//
+//	CAT
+//	O#O
+//	WOE
+func testPuzzle() *data.Puzzle {
+	return &data.Puzzle{
+		Title:       "Farmyard",
+		Description: "A small one",
+		Width:       3,
+		Height:      3,
+		Content: data.PuzzleData{
+			Across: map[string]data.ClueData{
+				"1": {Row: 0, Col: 0, Clue: "Feline", Answer: "CAT"},
+				"3": {Row: 2, Col: 0, Clue: "Misery", Answer: "WOE"},
+			},
+			Down: map[string]data.ClueData{
+				"1": {Row: 0, Col: 0, Clue: "Dairy animal", Answer: "COW"},
+				"2": {Row: 0, Col: 2, Clue: "Foot digit", Answer: "TOE"},
+			},
+			Circles: []grid.Square{{Row: 2, Col: 1}},
+		},
+		Notes: "Notes",
+	}
+}
+
+func checkPuzzle(t *testing.T, got, want *data.Puzzle) {
+	t.Helper()
+
+	if got.Title != want.Title || got.Description != want.Description || got.Notes != want.Notes {
+		t.Errorf("got title %q, description %q and notes %q; want %q, %q and %q",
+			got.Title, got.Description, got.Notes, want.Title, want.Description, want.Notes)
+	}
+	if got.Width != want.Width || got.Height != want.Height {
+		t.Errorf("got %dx%d; want %dx%d", got.Width, got.Height, want.Width, want.Height)
+	}
+	if got.Difficulty != want.Difficulty {
+		t.Errorf("got difficulty %d; want %d", got.Difficulty, want.Difficulty)
+	}
+	if !reflect.DeepEqual(got.Content, want.Content) {
+		t.Errorf("got content %+v; want %+v", got.Content, want.Content)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	valid, err := json.Marshal(NewFile(testPuzzle()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"Valid", string(valid), nil},
+		{"Not JSON", "CAT", nil},
+		{"Unknown field", `{"title": "Cats", "colour": "red"}`, nil},
+		{"Crossing answers disagree", `{"width": 3, "height": 3, "content": {
+			"across": {"1": {"row": 0, "col": 0, "answer": "CAT"}},
+			"down": {"1": {"row": 0, "col": 0, "answer": "DOG"}}}}`, grid.ErrConflict},
+		{"Answer off the grid", `{"width": 3, "height": 3, "content": {
+			"across": {"1": {"row": 0, "col": 1, "answer": "CAT"}}}}`, nil},
+		{"Too large", `{"width": 100000, "height": 100000}`, grid.ErrSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			puzzle, err := ReadJSON([]byte(tt.input))
+			if tt.name == "Valid" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkPuzzle(t, puzzle, testPuzzle())
+				return
+			}
+			if err == nil {
+				t.Fatal("got no error; want one")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+const testIPUZ = `{
+	"version": "http://ipuz.org/v2",
+	"kind": ["http://ipuz.org/crossword#1"],
+	"title": "Farmyard",
+	"intro": "A small one",
+	"notes": "Notes",
+	"difficulty": "3",
+	"dimensions": {"width": 3, "height": 3},
+	"puzzle": [[1, 0, "2"], [0, "#", 0], [3, {"cell": 0, "style": {"shapebg": "circle"}}, 0]],
+	"solution": [["C", "A", "T"], ["O", "#", "O"], ["W", {"value": "o"}, "E"]],
+	"clues": {
+		"Across": [[1, "Feline"], {"number": 3, "clue": "Misery"}],
+		"Down:Down": [["1", "Dairy animal"], [2, "Foot digit"]]
+	}
+}`
+
+func TestReadIPUZ(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace []string
+		wantErr error
+	}{
+		{"Valid", nil, nil},
+		{"Not JSON", []string{`"version"`, `version`}, nil},
+		{"Not a crossword", []string{"crossword#1", "sudoku#1"}, nil},
+		{"Too large", []string{`"width": 3`, `"width": 100000`}, grid.ErrSize},
+		{"Rows missing", []string{`[0, "#", 0], `, ``}, nil},
+		{"Row too short", []string{`[0, "#", 0]`, `[0, "#"]`}, nil},
+		{"No solution", []string{`"solution"`, `"answers"`}, ErrNoSolution},
+		{"Blank solution square", []string{`["O", "#", "O"]`, `["O", "#", null]`}, ErrNoSolution},
+		{"Rebus", []string{`"T"`, `"TH"`}, nil},
+		{"Invalid letter", []string{`"T"`, `"7"`}, nil},
+		{"Invalid square", []string{`"2"]`, `true]`}, nil},
+		{"Invalid clue", []string{`[2, "Foot digit"]`, `[2]`}, nil},
+		{"Clue without a square", []string{`[2, "Foot digit"]`, `[9, "Foot digit"]`}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := testIPUZ
+			if tt.replace != nil {
+				input = strings.Replace(input, tt.replace[0], tt.replace[1], 1)
+			}
+			puzzle, err := ReadIPUZ([]byte(input))
+			if tt.replace == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := testPuzzle()
+				want.Difficulty = 3
+				checkPuzzle(t, puzzle, want)
+				return
+			}
+			if err == nil {
+				t.Fatal("got no error; want one")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type puzSection struct {
+	name string
+	data []byte
+}
+
+// buildPUZ writes an Across Lite file. The checksums are left as zero since
+// they aren't checked when reading.
+func buildPUZ(width, height int, solution string, strs []string, sections ...puzSection) []byte {
+	header := make([]byte, puzHeaderSize)
+	copy(header[puzMagicOffset:], puzMagic)
+	header[puzWidthOffset] = byte(width)
+	header[puzHeightOffset] = byte(height)
+	binary.LittleEndian.PutUint16(header[puzClueCountOffset:], uint16(len(strs)-4))
+
+	var b bytes.Buffer
+	b.Write(header)
+	b.WriteString(solution)
+	for _, cell := range []byte(solution) {
+		if cell == '.' {
+			b.WriteByte('.')
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	for _, s := range strs {
+		b.WriteString(s)
+		b.WriteByte(0)
+	}
+	for _, section := range sections {
+		b.WriteString(section.name)
+		b.Write(binary.LittleEndian.AppendUint16(nil, uint16(len(section.data))))
+		b.Write([]byte{0, 0})
+		b.Write(section.data)
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+func TestReadPUZ(t *testing.T) {
+	// title, author, copyright, the clues in order and the notes
+	strs := []string{"Farmyard", "Alice", "A small one", "Feline", "Dairy animal", "Foot digit", "Misery", "Notes"}
+	circles := puzSection{"GEXT", []byte{0, 0, 0, 0, 0, 0, 0, puzCircled, 0}}
+	valid := buildPUZ(3, 3, "CATO.OWOE", strs, circles)
+
+	scrambled := buildPUZ(3, 3, "CATO.OWOE", strs)
+	scrambled[puzScrambledOffset] = 4
+
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr error
+	}{
+		{"Valid", valid, nil},
+		{"Empty", nil, errBadPUZ},
+		{"Not a .puz file", []byte(strings.Repeat("CAT", 100)), errBadPUZ},
+		{"Scrambled", scrambled, errScrambled},
+		{"Grid cut short", valid[:puzHeaderSize+10], errBadPUZ},
+		{"Clues cut short", valid[:bytes.Index(valid, []byte("Foot"))], errBadPUZ},
+		{"Too large", buildPUZ(60, 1, strings.Repeat("A", 60), strs), grid.ErrSize},
+		{"Wrong clue count", buildPUZ(3, 3, "CATO.OWOE", strs[:len(strs)-1]), nil},
+		{"Unknown letters", buildPUZ(3, 3, "CA-O.OWOE", strs), ErrNoSolution},
+		{"Rebus", buildPUZ(3, 3, "CATO.OWOE", strs, puzSection{"GRBS", make([]byte, 9)}), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			puzzle, err := ReadPUZ(tt.input)
+			if tt.name == "Valid" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkPuzzle(t, puzzle, testPuzzle())
+				return
+			}
+			if err == nil {
+				t.Fatal("got no error; want one")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	jsonFile, err := json.Marshal(NewFile(testPuzzle()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		input   []byte
+		wantErr error
+	}{
+		{"Our JSON", "cats.json", jsonFile, nil},
+		{"ipuz", "cats.ipuz", []byte(testIPUZ), nil},
+		{"ipuz as JSON", "CATS.JSON", []byte(testIPUZ), nil},
+		{"Across Lite", "cats.puz", buildPUZ(3, 3, "CATO.OWOE", []string{"Farmyard", "", "", "Feline", "Dairy animal", "Foot digit", "Misery", ""}), nil},
+		{"Unsupported", "cats.txt", []byte("CAT"), ErrUnsupported},
+		{"No extension", "cats", jsonFile, ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			puzzle, err := Read(tt.file, tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v; want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if puzzle.Title != "Farmyard" {
+				t.Errorf("got title %q; want %q", puzzle.Title, "Farmyard")
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	for _, solution := range []bool{false, true} {
+		p := testPuzzle(t)
+		var buf bytes.Buffer
+		err := PNG(&buf, p, solution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte("\x89PNG\r\n\x1a\n")) {
+			t.Fatalf("solution %t: output doesn't start with a PNG signature", solution)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := image.Rect(0, 0, p.Grid.Width*pngCell+1, p.Grid.Height*pngCell+1)
+		if img.Bounds() != want {
+			t.Errorf("solution %t: got bounds %v; want %v", solution, img.Bounds(), want)
+		}
+	}
+}
+
+func TestShareCard(t *testing.T) {
+	p := testPuzzle(t)
+	p.Title = strings.Repeat("A very long title ", 20)
+
+	var buf bytes.Buffer
+	err := ShareCard(&buf, p, 3725)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := image.Rect(0, 0, ShareCardWidth, ShareCardHeight)
+	if img.Bounds() != want {
+		t.Errorf("got bounds %v; want %v", img.Bounds(), want)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "0:00"},
+		{59, "0:59"},
+		{61, "1:01"},
+		{3599, "59:59"},
+		{3725, "1:02:05"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.seconds); got != tt.want {
+			t.Errorf("formatDuration(%d) = %q; want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	tests := []struct {
+		name        string
+		solution    bool
+		wantLetters int
+	}{
+		{"Blank", false, 0},
+		{"Solution", true, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPuzzle(t)
+			p.Title = `Cats & "Dogs" <3`
+			var buf bytes.Buffer
+			err := SVG(&buf, p, tt.solution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(buf.String(), `<svg xmlns="http://www.w3.org/2000/svg" viewBox="-1 -1 110 110"`) {
+				t.Errorf("output starts with %q; want an svg element sized to the grid", buf.String()[:min(buf.Len(), 80)])
+			}
+
+			// the output must be well formed, with the title escaped
+			var title string
+			numbers, letters, rects, circles := 0, 0, 0, 0
+			dec := xml.NewDecoder(&buf)
+			for {
+				tok, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				start, ok := tok.(xml.StartElement)
+				if !ok {
+					continue
+				}
+				switch start.Name.Local {
+				case "title":
+					err = dec.DecodeElement(&title, &start)
+					if err != nil {
+						t.Fatal(err)
+					}
+				case "rect":
+					rects++
+				case "circle":
+					circles++
+				case "text":
+					var text string
+					err = dec.DecodeElement(&text, &start)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if text >= "A" && text <= "Z" {
+						letters++
+					} else {
+						numbers++
+					}
+				}
+			}
+
+			if title != p.Title {
+				t.Errorf("got title %q; want %q", title, p.Title)
+			}
+			// a background, one per square and the border
+			if rects != 11 {
+				t.Errorf("got %d rects; want 11", rects)
+			}
+			if circles != 1 {
+				t.Errorf("got %d circles; want 1", circles)
+			}
+			if numbers != 3 {
+				t.Errorf("got %d numbers; want 3", numbers)
+			}
+			if letters != tt.wantLetters {
+				t.Errorf("got %d letters; want %d", letters, tt.wantLetters)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type pdfFont int
+
+const (
+	regular pdfFont = iota
+	bold
+)
+
+// canvas collects the drawing operators for one page or panel. Coordinates
+// are given from the top left in points and flipped to PDF's bottom left
+// origin as they're written.
+type canvas struct {
+	width  float64
+	height float64
+	ops    bytes.Buffer
+}
+
+func newCanvas(width, height float64) *canvas {
+	return &canvas{width: width, height: height}
+}
+
+func (c *canvas) printf(format string, args ...any) {
+	fmt.Fprintf(&c.ops, format, args...)
+}
+
+func (c *canvas) fillRect(x, y, w, h, gray float64) {
+	c.printf("%s g %s %s %s %s re f\n", num(gray), num(x), num(c.height-y-h), num(w), num(h))
+}
+
+func (c *canvas) strokeRect(x, y, w, h, lineWidth float64) {
+	c.printf("%s w %s %s %s %s re S\n", num(lineWidth), num(x), num(c.height-y-h), num(w), num(h))
+}
+
+func (c *canvas) line(x1, y1, x2, y2, lineWidth float64, dashed bool) {
+	if dashed {
+		c.printf("[3 3] 0 d ")
+	}
+	c.printf("%s w %s %s m %s %s l S", num(lineWidth), num(x1), num(c.height-y1), num(x2), num(c.height-y2))
+	if dashed {
+		c.printf(" [] 0 d")
+	}
+	c.printf("\n")
+}
+
+// circle strokes a circle approximated by four Bézier curves.
+func (c *canvas) circle(cx, cy, r, lineWidth float64) {
+	k := 0.5523 * r
+	y := c.height - cy
+	c.printf("%s w %s %s m\n", num(lineWidth), num(cx+r), num(y))
+	c.printf("%s %s %s %s %s %s c\n", num(cx+r), num(y+k), num(cx+k), num(y+r), num(cx), num(y+r))
+	c.printf("%s %s %s %s %s %s c\n", num(cx-k), num(y+r), num(cx-r), num(y+k), num(cx-r), num(y))
+	c.printf("%s %s %s %s %s %s c\n", num(cx-r), num(y-k), num(cx-k), num(y-r), num(cx), num(y-r))
+	c.printf("%s %s %s %s %s %s c S\n", num(cx+k), num(y-r), num(cx+r), num(y-k), num(cx+r), num(y))
+}
+
+// text draws a string with its baseline at y.
+func (c *canvas) text(x, y float64, f pdfFont, size float64, s string) {
+	c.printf("0 g BT /F%d %s Tf %s %s Td (%s) Tj ET\n", f+1, num(size), num(x), num(c.height-y), escapeText(winAnsi(s)))
+}
+
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-" {
+		return "0"
+	}
+	return s
+}
+
+// pdfDocument is a minimal PDF writer covering what the print layouts need.
+// Text is set in the standard Helvetica fonts, which every reader provides,
+// so no fonts are embedded.
+type pdfDocument struct {
+	title string
+	pages []pdfPage
+}
+
+type pdfPage struct {
+	width   float64
+	height  float64
+	content []byte
+}
+
+func (d *pdfDocument) addPage(width, height float64, content []byte) {
+	d.pages = append(d.pages, pdfPage{width: width, height: height, content: content})
+}
+
+type countingWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
+func (d *pdfDocument) writeTo(w io.Writer) error {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, cw.n)
+		fmt.Fprintf(cw, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	fmt.Fprint(cw, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// objects 1 to 5 are fixed; each page then takes a page and a content
+	// object
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (badwords) >>", escapeText(winAnsi(d.title))))
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(page.width), num(page.height), 7+2*i))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		_, err := zw.Write(page.content)
+		if err != nil {
+			return err
+		}
+		err = zw.Close()
+		if err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(cw, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return cw.w.Flush()
+}
+
+// winAnsiSpecials maps the typographic characters that are common in clues to
+// their code in the WinAnsi encoding used by the standard fonts.
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// winAnsi encodes a string for the standard fonts. Characters they can't show
+// are replaced with a question mark.
+func winAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 32 && r < 127:
+			out = append(out, byte(r))
+		case r < 32:
+			out = append(out, ' ')
+		case r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		default:
+			b, ok := winAnsiSpecials[r]
+			if !ok {
+				b = '?'
+			}
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+func escapeText(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c == '(' || c == ')' || c == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// Widths of the printable ASCII characters in thousandths of the font size,
+// from the Adobe metrics for the standard fonts.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// textWidth measures a string in points. Characters outside printable ASCII
+// are counted at the width of a digit, which is close enough for wrapping.
+func textWidth(f pdfFont, size float64, s string) float64 {
+	widths := &helveticaWidths
+	if f == bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, b := range winAnsi(s) {
+		if b >= 32 && b < 127 {
+			total += widths[b-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrap breaks text into lines no wider than width.
+func wrap(f pdfFont, size, width float64, text string) []string {
+	return wrapWith(func(s string) float64 { return textWidth(f, size, s) }, width, text)
+}
+
+// wrapWith breaks text into lines that measure no more than width, splitting
+// words that are too long to fit on a line of their own.
+func wrapWith(measure func(string) float64, width float64, text string) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if measure(candidate) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = ""
+		for measure(word) > width {
+			runes := []rune(word)
+			cut := 1
+			for cut < len(runes) && measure(string(runes[:cut+1])) <= width {
+				cut++
+			}
+			lines = append(lines, string(runes[:cut]))
+			word = string(runes[cut:])
+		}
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
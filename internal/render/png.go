@@ -0,0 +1,225 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/ggetzie/badwords_be/internal/grid"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	pngCell = 36
+	// ShareCardWidth and ShareCardHeight are the size Open Graph previews
+	// are shown at.
+	ShareCardWidth  = 1200
+	ShareCardHeight = 630
+)
+
+var (
+	black  = color.Gray{0}
+	white  = color.Gray{255}
+	accent = color.RGBA{0x2b, 0x6c, 0xb0, 0xff}
+
+	fontsOnce sync.Once
+	fonts     struct {
+		regular, bold *opentype.Font
+		err           error
+	}
+)
+
+func loadFonts() (regularFont, boldFont *opentype.Font, err error) {
+	fontsOnce.Do(func() {
+		fonts.regular, fonts.err = opentype.Parse(goregular.TTF)
+		if fonts.err == nil {
+			fonts.bold, fonts.err = opentype.Parse(gobold.TTF)
+		}
+	})
+	return fonts.regular, fonts.bold, fonts.err
+}
+
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// drawText draws s with its baseline at x, y.
+func drawText(img draw.Image, face font.Face, col color.Color, x, y int, s string) {
+	d := font.Drawer{Dst: img, Src: image.NewUniform(col), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+func fillRect(img draw.Image, r image.Rectangle, col color.Color) {
+	draw.Draw(img, r, image.NewUniform(col), image.Point{}, draw.Src)
+}
+
+// strokeRect draws a rectangle's outline of the given thickness inside r.
+func strokeRect(img draw.Image, r image.Rectangle, thickness int, col color.Color) {
+	fillRect(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+thickness), col)
+	fillRect(img, image.Rect(r.Min.X, r.Max.Y-thickness, r.Max.X, r.Max.Y), col)
+	fillRect(img, image.Rect(r.Min.X, r.Min.Y, r.Min.X+thickness, r.Max.Y), col)
+	fillRect(img, image.Rect(r.Max.X-thickness, r.Min.Y, r.Max.X, r.Max.Y), col)
+}
+
+// strokeCircle draws a one pixel ring centred in the square r.
+func strokeCircle(img draw.Image, r image.Rectangle, col color.Color) {
+	cx, cy := float64(r.Min.X+r.Max.X)/2, float64(r.Min.Y+r.Max.Y)/2
+	radius := float64(r.Dx()) * 0.45
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			d := dx*dx + dy*dy
+			if d >= (radius-0.6)*(radius-0.6) && d <= (radius+0.6)*(radius+0.6) {
+				img.Set(x, y, col)
+			}
+		}
+	}
+}
+
+// PNG writes the grid as an image with its numbers and circles. The letters
+// are only drawn when solution is set.
+func PNG(w io.Writer, p Puzzle, solution bool) error {
+	regularFont, _, err := loadFonts()
+	if err != nil {
+		return err
+	}
+	numberFace, err := newFace(regularFont, pngCell*0.3)
+	if err != nil {
+		return err
+	}
+	letterFace, err := newFace(regularFont, pngCell*0.6)
+	if err != nil {
+		return err
+	}
+
+	g := p.Grid
+	img := image.NewRGBA(image.Rect(0, 0, g.Width*pngCell+1, g.Height*pngCell+1))
+	fillRect(img, img.Bounds(), white)
+	for r := 0; r < g.Height; r++ {
+		for c := 0; c < g.Width; c++ {
+			square := image.Rect(c*pngCell, r*pngCell, (c+1)*pngCell+1, (r+1)*pngCell+1)
+			cell := g.At(r, c)
+			if cell == grid.Block {
+				fillRect(img, square, black)
+				continue
+			}
+			strokeRect(img, square, 1, black)
+			if n, ok := p.Numbers[grid.Square{Row: r, Col: c}]; ok {
+				drawText(img, numberFace, black, square.Min.X+3, square.Min.Y+pngCell*3/10+1, strconv.Itoa(n))
+			}
+			if solution && cell != grid.Blank {
+				letter := string(cell)
+				advance := font.MeasureString(letterFace, letter).Round()
+				drawText(img, letterFace, black, square.Min.X+(pngCell-advance)/2, square.Min.Y+pngCell*7/8, letter)
+			}
+		}
+	}
+	for _, sq := range p.Circles {
+		if g.IsWhite(sq.Row, sq.Col) {
+			strokeCircle(img, image.Rect(sq.Col*pngCell, sq.Row*pngCell, (sq.Col+1)*pngCell, (sq.Row+1)*pngCell), black)
+		}
+	}
+	strokeRect(img, img.Bounds(), 2, black)
+	return png.Encode(w, img)
+}
+
+// ShareCard writes an image announcing a finished solve: the puzzle's title,
+// the solve time and the grid's shape with every square filled in but no
+// letters, so it gives nothing away.
+func ShareCard(w io.Writer, p Puzzle, seconds int) error {
+	regularFont, boldFont, err := loadFonts()
+	if err != nil {
+		return err
+	}
+	titleFace, err := newFace(boldFont, 56)
+	if err != nil {
+		return err
+	}
+	timeFace, err := newFace(boldFont, 96)
+	if err != nil {
+		return err
+	}
+	textFace, err := newFace(regularFont, 36)
+	if err != nil {
+		return err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, ShareCardWidth, ShareCardHeight))
+	fillRect(img, img.Bounds(), white)
+	fillRect(img, image.Rect(0, 0, ShareCardWidth, 16), accent)
+
+	const margin = 64
+	g := p.Grid
+	size := ShareCardHeight - 2*margin
+	cell := size / max(g.Width, g.Height)
+	gx := ShareCardWidth - margin - cell*g.Width
+	gy := (ShareCardHeight - cell*g.Height) / 2
+	for r := 0; r < g.Height; r++ {
+		for c := 0; c < g.Width; c++ {
+			square := image.Rect(gx+c*cell, gy+r*cell, gx+(c+1)*cell+1, gy+(r+1)*cell+1)
+			if g.At(r, c) == grid.Block {
+				fillRect(img, square, black)
+				continue
+			}
+			fillRect(img, square, accent)
+			strokeRect(img, square, 1, white)
+		}
+	}
+	strokeRect(img, image.Rect(gx, gy, gx+cell*g.Width+1, gy+cell*g.Height+1), 2, black)
+
+	textArea := gx - 2*margin
+	y := margin + 56
+	for _, line := range wrapFace(titleFace, textArea, p.Title, 3) {
+		drawText(img, titleFace, black, margin, y, line)
+		y += 68
+	}
+	if p.Author != "" {
+		drawText(img, textFace, black, margin, y, truncateFace(textFace, textArea, "by "+p.Author))
+	}
+	drawText(img, textFace, black, margin, ShareCardHeight-margin-120, "Solved in")
+	drawText(img, timeFace, accent, margin, ShareCardHeight-margin, formatDuration(seconds))
+	return png.Encode(w, img)
+}
+
+// formatDuration shows a solve time as m:ss, or h:mm:ss from an hour up.
+func formatDuration(seconds int) string {
+	h, m, s := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// wrapFace breaks text into at most maxLines lines no wider than width,
+// shortening the last one if the text doesn't fit.
+func wrapFace(face font.Face, width int, text string, maxLines int) []string {
+	measure := func(s string) float64 {
+		return float64(font.MeasureString(face, s).Round())
+	}
+	lines := wrapWith(measure, float64(width), text)
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] = truncateFace(face, width, lines[maxLines-1]+"…")
+	}
+	return lines
+}
+
+func truncateFace(face font.Face, width int, s string) string {
+	runes := []rune(s)
+	if font.MeasureString(face, s).Round() <= width {
+		return s
+	}
+	for len(runes) > 0 && font.MeasureString(face, string(runes)+"…").Round() > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
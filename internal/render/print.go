@@ -0,0 +1,257 @@
+package render
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+// Clue is a numbered clue as it's printed.
+type Clue struct {
+	Number int
+	Text   string
+}
+
+// Puzzle holds what the renderers need to draw a puzzle. Grid carries the
+// solution letters, which are only drawn where a layout asks for them.
+type Puzzle struct {
+	Title   string
+	Author  string
+	Grid    *grid.Grid
+	Numbers map[grid.Square]int
+	Circles []grid.Square
+	Across  []Clue
+	Down    []Clue
+}
+
+type PrintOptions struct {
+	Solution   bool
+	LargePrint bool
+	TwoUp      bool
+}
+
+// printStyle sizes one panel of the layout. A panel is a whole page except
+// in two-up printing, where a landscape page holds two copies side by side.
+type printStyle struct {
+	pageWidth  float64
+	pageHeight float64
+	panels     int
+	margin     float64
+	columns    int
+	clueSize   float64
+	titleSize  float64
+	gridShare  float64
+	maxCell    float64
+}
+
+var (
+	standardStyle   = printStyle{612, 792, 1, 36, 3, 9, 16, 0.55, 32}
+	largePrintStyle = printStyle{612, 792, 1, 36, 2, 14, 22, 0.8, 54}
+	twoUpStyle      = printStyle{792, 612, 2, 24, 2, 7, 12, 0.5, 22}
+)
+
+// PDF writes a print layout of the puzzle: the title, grid and clues in
+// columns, running onto further pages when the clues don't fit, followed
+// by the solution if it's asked for.
+func PDF(w io.Writer, p Puzzle, opts PrintOptions) error {
+	style := standardStyle
+	switch {
+	case opts.LargePrint:
+		style = largePrintStyle
+	case opts.TwoUp:
+		style = twoUpStyle
+	}
+
+	panels := style.puzzlePanels(p)
+	if opts.Solution {
+		panels = append(panels, style.solutionPanel(p))
+	}
+
+	doc := &pdfDocument{title: p.Title}
+	panelWidth := style.pageWidth / float64(style.panels)
+	for _, panel := range panels {
+		if style.panels == 1 {
+			doc.addPage(style.pageWidth, style.pageHeight, panel.ops.Bytes())
+			continue
+		}
+		page := newCanvas(style.pageWidth, style.pageHeight)
+		for i := 0; i < style.panels; i++ {
+			page.printf("q 1 0 0 1 %s 0 cm\n", num(panelWidth*float64(i)))
+			page.ops.Write(panel.ops.Bytes())
+			page.printf("Q\n")
+			if i > 0 {
+				x := panelWidth * float64(i)
+				page.line(x, style.margin, x, style.pageHeight-style.margin, 0.5, true)
+			}
+		}
+		doc.addPage(style.pageWidth, style.pageHeight, page.ops.Bytes())
+	}
+	return doc.writeTo(w)
+}
+
+func (s printStyle) newPanel() *canvas {
+	return newCanvas(s.pageWidth/float64(s.panels), s.pageHeight)
+}
+
+func (s printStyle) contentWidth() float64 {
+	return s.pageWidth/float64(s.panels) - 2*s.margin
+}
+
+// header draws the title and byline and returns the y position below them.
+func (s printStyle) header(c *canvas, title, author string) float64 {
+	y := s.margin
+	for _, line := range wrap(bold, s.titleSize, s.contentWidth(), title) {
+		y += s.titleSize * 1.2
+		c.text(s.margin, y, bold, s.titleSize, line)
+	}
+	if author != "" {
+		size := s.clueSize + 1
+		y += size * 1.5
+		c.text(s.margin, y, regular, size, "by "+author)
+	}
+	return y + s.clueSize
+}
+
+// placeGrid works out the square size and position for a grid centred in the
+// content width and at most maxHeight tall.
+func (s printStyle) placeGrid(g *grid.Grid, maxHeight float64) (x, cell float64) {
+	cell = min(s.contentWidth()/float64(g.Width), maxHeight/float64(g.Height), s.maxCell)
+	x = s.margin + (s.contentWidth()-cell*float64(g.Width))/2
+	return x, cell
+}
+
+func (s printStyle) puzzlePanels(p Puzzle) []*canvas {
+	panel := s.newPanel()
+	top := s.header(panel, p.Title, p.Author)
+	x, cell := s.placeGrid(p.Grid, (s.pageHeight-2*s.margin)*s.gridShare)
+	drawGrid(panel, p, x, top, cell, false)
+
+	f := &clueFlow{
+		style:   s,
+		panels:  []*canvas{panel},
+		columnW: (s.contentWidth() - float64(s.columns-1)*s.clueSize*1.5) / float64(s.columns),
+		top:     top + cell*float64(p.Grid.Height) + s.clueSize*2,
+	}
+	f.y = f.top
+	f.section("ACROSS", p.Across)
+	f.section("DOWN", p.Down)
+	return f.panels
+}
+
+func (s printStyle) solutionPanel(p Puzzle) *canvas {
+	panel := s.newPanel()
+	top := s.header(panel, "Solution: "+p.Title, "")
+	x, cell := s.placeGrid(p.Grid, s.pageHeight-s.margin-top)
+	drawGrid(panel, p, x, top, cell, true)
+	return panel
+}
+
+// drawGrid draws the squares, numbers and circles of a grid with its top left
+// corner at x, y, filling in the letters when solution is set.
+func drawGrid(c *canvas, p Puzzle, x, y, cell float64, solution bool) {
+	g := p.Grid
+	numberSize := cell * 0.3
+	letterSize := cell * 0.6
+	for r := 0; r < g.Height; r++ {
+		for col := 0; col < g.Width; col++ {
+			sx, sy := x+float64(col)*cell, y+float64(r)*cell
+			square := g.At(r, col)
+			if square == grid.Block {
+				c.fillRect(sx, sy, cell, cell, 0)
+				continue
+			}
+			c.strokeRect(sx, sy, cell, cell, 0.5)
+			if n, ok := p.Numbers[grid.Square{Row: r, Col: col}]; ok {
+				c.text(sx+cell*0.06, sy+cell*0.06+numberSize, regular, numberSize, strconv.Itoa(n))
+			}
+			if solution && square != grid.Blank {
+				letter := string(square)
+				c.text(sx+(cell-textWidth(regular, letterSize, letter))/2, sy+cell*0.85, regular, letterSize, letter)
+			}
+		}
+	}
+	for _, sq := range p.Circles {
+		if g.IsWhite(sq.Row, sq.Col) {
+			c.circle(x+(float64(sq.Col)+0.5)*cell, y+(float64(sq.Row)+0.5)*cell, cell*0.45, 0.5)
+		}
+	}
+	c.strokeRect(x, y, cell*float64(g.Width), cell*float64(g.Height), 1.5)
+}
+
+// clueFlow sets clues into columns, moving to the next column and then to a
+// new panel as each one fills. Clues are never split across columns.
+type clueFlow struct {
+	style   printStyle
+	panels  []*canvas
+	columnW float64
+	top     float64
+	column  int
+	y       float64
+}
+
+func (f *clueFlow) leading() float64 {
+	return f.style.clueSize * 1.25
+}
+
+// reserve makes room for a block of the given height, starting a new column
+// or panel if the current column can't hold it.
+func (f *clueFlow) reserve(height float64) {
+	bottom := f.style.pageHeight - f.style.margin
+	// a block taller than a whole column is set at the top of one anyway
+	for f.y+height > bottom && f.y != f.style.margin {
+		f.column++
+		if f.column == f.style.columns {
+			f.panels = append(f.panels, f.style.newPanel())
+			f.column = 0
+			f.top = f.style.margin
+		}
+		f.y = f.top
+	}
+}
+
+func (f *clueFlow) x() float64 {
+	return f.style.margin + float64(f.column)*(f.columnW+f.style.clueSize*1.5)
+}
+
+func (f *clueFlow) section(heading string, clues []Clue) {
+	if len(clues) == 0 {
+		return
+	}
+	size := f.style.clueSize
+	headingSize := size * 1.1
+	if f.y != f.top {
+		f.y += f.leading() * 0.6
+	}
+
+	gutter := textWidth(bold, size, "000") + size*0.4
+	for i, clue := range clues {
+		lines := wrap(regular, size, f.columnW-gutter, clue.Text)
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		height := float64(len(lines)) * f.leading()
+		if i == 0 {
+			// keep the heading with the first clue
+			f.reserve(headingSize*1.5 + height)
+			f.y += headingSize * 1.2
+			f.panel().text(f.x(), f.y, bold, headingSize, heading)
+			f.y += headingSize * 0.3
+		} else {
+			f.reserve(height)
+		}
+
+		number := strconv.Itoa(clue.Number)
+		x := f.x()
+		f.panel().text(x+gutter-size*0.4-textWidth(bold, size, number), f.y+size, bold, size, number)
+		for _, line := range lines {
+			f.y += f.leading()
+			f.panel().text(x+gutter, f.y-f.leading()+size, regular, size, line)
+		}
+		f.y += size * 0.25
+	}
+}
+
+func (f *clueFlow) panel() *canvas {
+	return f.panels[len(f.panels)-1]
+}
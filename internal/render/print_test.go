@@ -0,0 +1,107 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+func testPuzzle(t *testing.T) Puzzle {
+	t.Helper()
+
+	g, err := grid.Parse([]string{"CAT", "O#O", "WOE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Puzzle{
+		Title:   "Test Puzzle — “quoted”",
+		Author:  "Alice",
+		Grid:    g,
+		Numbers: map[grid.Square]int{},
+		Circles: []grid.Square{{Row: 0, Col: 0}},
+		Across:  []Clue{{1, "Feline"}, {3, "Misery"}},
+		Down:    []Clue{{1, "Dairy animal"}, {2, "Foot digit"}},
+	}
+	for _, e := range g.Entries() {
+		p.Numbers[grid.Square{Row: e.Row, Col: e.Col}] = e.Number
+	}
+	return p
+}
+
+var (
+	pdfPageCountRX = regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`)
+	pdfMediaBoxRX  = regexp.MustCompile(`/MediaBox \[0 0 (\d+) (\d+)\]`)
+	pdfStartXrefRX = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+)
+
+func TestPDF(t *testing.T) {
+	long := testPuzzle(t)
+	long.Across = nil
+	for i := 1; i <= 200; i++ {
+		long.Across = append(long.Across, Clue{i, strings.Repeat("A rather long clue that wraps ", 3)})
+	}
+
+	tests := []struct {
+		name       string
+		puzzle     Puzzle
+		opts       PrintOptions
+		wantPages  int
+		wantWidth  string
+		wantHeight string
+	}{
+		{"Standard", testPuzzle(t), PrintOptions{}, 1, "612", "792"},
+		{"Solution", testPuzzle(t), PrintOptions{Solution: true}, 2, "612", "792"},
+		{"Large print", testPuzzle(t), PrintOptions{LargePrint: true}, 1, "612", "792"},
+		{"Two up", testPuzzle(t), PrintOptions{TwoUp: true}, 1, "792", "612"},
+		{"Clues run onto more pages", long, PrintOptions{}, 4, "612", "792"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := PDF(&buf, tt.puzzle, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := buf.Bytes()
+
+			if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) {
+				t.Errorf("output starts with %q; want a PDF header", out[:min(len(out), 16)])
+			}
+			m := pdfStartXrefRX.FindSubmatch(out)
+			if m == nil {
+				t.Fatal("output doesn't end with startxref and the end of file marker")
+			}
+			xref, _ := strconv.Atoi(string(m[1]))
+			if xref >= len(out) || !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+				t.Errorf("startxref %d doesn't point at the cross-reference table", xref)
+			}
+
+			m = pdfPageCountRX.FindSubmatch(out)
+			if m == nil {
+				t.Fatal("output has no page tree")
+			}
+			if got := string(m[1]); got != fmt.Sprint(tt.wantPages) {
+				t.Errorf("got %s pages; want %d", got, tt.wantPages)
+			}
+			for _, box := range pdfMediaBoxRX.FindAllSubmatch(out, -1) {
+				if string(box[1]) != tt.wantWidth || string(box[2]) != tt.wantHeight {
+					t.Errorf("got a %sx%s page; want %sx%s", box[1], box[2], tt.wantWidth, tt.wantHeight)
+				}
+			}
+		})
+	}
+}
+
+func TestWinAnsi(t *testing.T) {
+	got := winAnsi("Café “quoted” — ☃")
+	want := []byte("Caf\xe9 \x93quoted\x94 \x97 ?")
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
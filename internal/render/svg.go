@@ -0,0 +1,64 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ggetzie/badwords_be/internal/grid"
+)
+
+// svgCell is the size of a square in SVG user units; the image scales to
+// whatever size it's shown at.
+const svgCell = 36
+
+// SVG writes the grid as a scalable image with its numbers and circles. The
+// letters are only drawn when solution is set.
+func SVG(w io.Writer, p Puzzle, solution bool) error {
+	g := p.Grid
+	width, height := g.Width*svgCell, g.Height*svgCell
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="-1 -1 %d %d" width="%d" height="%d">`+"\n", width+2, height+2, width+2, height+2)
+	fmt.Fprintf(bw, "<title>%s</title>\n", escapeXML(p.Title))
+	fmt.Fprintf(bw, `<rect x="0" y="0" width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+	fmt.Fprint(bw, `<g stroke="#000" stroke-width="1">`+"\n")
+	for r := 0; r < g.Height; r++ {
+		for c := 0; c < g.Width; c++ {
+			fill := "#fff"
+			if g.At(r, c) == grid.Block {
+				fill = "#000"
+			}
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", c*svgCell, r*svgCell, svgCell, svgCell, fill)
+		}
+	}
+	for _, sq := range p.Circles {
+		if g.IsWhite(sq.Row, sq.Col) {
+			fmt.Fprintf(bw, `<circle cx="%d" cy="%d" r="%s" fill="none"/>`+"\n", sq.Col*svgCell+svgCell/2, sq.Row*svgCell+svgCell/2, num(svgCell*0.45))
+		}
+	}
+	fmt.Fprintf(bw, `<rect x="0" y="0" width="%d" height="%d" fill="none" stroke-width="2"/>`+"\n", width, height)
+	fmt.Fprint(bw, "</g>\n")
+
+	fmt.Fprint(bw, `<g font-family="Helvetica, Arial, sans-serif" fill="#000">`+"\n")
+	for r := 0; r < g.Height; r++ {
+		for c := 0; c < g.Width; c++ {
+			if n, ok := p.Numbers[grid.Square{Row: r, Col: c}]; ok {
+				fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d">%d</text>`+"\n", c*svgCell+2, r*svgCell+11, svgCell*3/10, n)
+			}
+			if cell := g.At(r, c); solution && cell != grid.Block && cell != grid.Blank {
+				fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%d" text-anchor="middle">%c</text>`+"\n", c*svgCell+svgCell/2, r*svgCell+svgCell*7/8, svgCell*3/5, cell)
+			}
+		}
+	}
+	fmt.Fprint(bw, "</g>\n</svg>\n")
+	return bw.Flush()
+}
+
+func escapeXML(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
@@ -0,0 +1,159 @@
+package wordlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MinScore     = 0
+	MaxScore     = 100
+	DefaultScore = 50
+	MaxWordLen   = 25
+	MaxNotesLen  = 500
+)
+
+// PatternRX matches a normalized lookup pattern: letters for known cells and
+// ? for unknown ones.
+var PatternRX = regexp.MustCompile(`^[A-Z?]{1,25}$`)
+
+type Entry struct {
+	Word  string `json:"word"`
+	Score int    `json:"score"`
+	Notes string `json:"notes"`
+}
+
+// LineError describes a line of an uploaded list that couldn't be parsed.
+type LineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Normalize converts a word to the form it takes in a grid: upper case
+// letters with spaces and punctuation removed, so "ice cream" and "ICE-CREAM"
+// both become "ICECREAM".
+func Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// NormalizePattern upper cases a pattern and accepts . and _ as alternatives
+// to ? for an unknown cell.
+func NormalizePattern(pattern string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '_':
+			return '?'
+		}
+		return unicode.ToUpper(r)
+	}, pattern)
+}
+
+// ValidWord reports whether a normalized word can be stored in a list.
+func ValidWord(word string) bool {
+	if word == "" || len(word) > MaxWordLen {
+		return false
+	}
+	for _, r := range word {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func ValidScore(score int) bool {
+	return score >= MinScore && score <= MaxScore
+}
+
+// Match reports whether a word fits a normalized pattern.
+func Match(pattern, word string) bool {
+	if len(pattern) != len(word) {
+		return false
+	}
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '?' && pattern[i] != word[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// LikePattern converts a normalized pattern into a SQL LIKE pattern.
+func LikePattern(pattern string) string {
+	return strings.ReplaceAll(pattern, "?", "_")
+}
+
+// Parse reads a word list in the WORD;SCORE;NOTES format used by most
+// crossword construction tools. The score and notes are optional, blank
+// lines and lines starting with # are skipped, and words are normalized. A
+// word that appears more than once keeps its last entry. Lines that can't be
+// used are reported rather than aborting the whole upload.
+func Parse(r io.Reader, maxWords int) ([]Entry, []LineError, error) {
+	var (
+		entries []Entry
+		errs    []LineError
+		index   = make(map[string]int)
+	)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, ";", 3)
+		entry := Entry{Word: Normalize(parts[0]), Score: DefaultScore}
+		if !ValidWord(entry.Word) {
+			errs = append(errs, LineError{line, fmt.Sprintf("word must contain between 1 and %d letters", MaxWordLen)})
+			continue
+		}
+		if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+			score, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil || !ValidScore(score) {
+				errs = append(errs, LineError{line, fmt.Sprintf("score must be an integer between %d and %d", MinScore, MaxScore)})
+				continue
+			}
+			entry.Score = score
+		}
+		if len(parts) > 2 {
+			entry.Notes = strings.TrimSpace(parts[2])
+			if utf8.RuneCountInString(entry.Notes) > MaxNotesLen {
+				errs = append(errs, LineError{line, fmt.Sprintf("notes must not be more than %d characters long", MaxNotesLen)})
+				continue
+			}
+		}
+
+		if i, exists := index[entry.Word]; exists {
+			entries[i] = entry
+			continue
+		}
+		if len(entries) >= maxWords {
+			return nil, nil, fmt.Errorf("word list must not contain more than %d words", maxWords)
+		}
+		index[entry.Word] = len(entries)
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return entries, errs, nil
+}
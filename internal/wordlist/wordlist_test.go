@@ -0,0 +1,162 @@
+package wordlist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"cat", "CAT"},
+		{"ice cream", "ICECREAM"},
+		{"ICE-CREAM", "ICECREAM"},
+		{"o'clock!", "OCLOCK"},
+		{"R2D2", "RD"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := Normalize(tt.word); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"c?t", "C?T"},
+		{"c.t", "C?T"},
+		{"c_t", "C?T"},
+		{"???", "???"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := NormalizePattern(tt.pattern); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		word    string
+		want    bool
+	}{
+		{"C?T", "CAT", true},
+		{"C?T", "COT", true},
+		{"???", "DOG", true},
+		{"C?T", "CAB", false},
+		{"C?T", "CART", false},
+		{"CAT?", "CAT", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.word, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.word); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		maxWords    int
+		wantEntries []Entry
+		wantErrors  []LineError
+		wantErr     bool
+	}{
+		{
+			name:        "Word only",
+			input:       "cat\n",
+			maxWords:    10,
+			wantEntries: []Entry{{Word: "CAT", Score: DefaultScore}},
+		},
+		{
+			name:     "Score and notes",
+			input:    "ice cream;60; dessert ;with;semicolons\n",
+			maxWords: 10,
+			wantEntries: []Entry{
+				{Word: "ICECREAM", Score: 60, Notes: "dessert ;with;semicolons"},
+			},
+		},
+		{
+			name:        "Blank score",
+			input:       "cat;;pet",
+			maxWords:    10,
+			wantEntries: []Entry{{Word: "CAT", Score: DefaultScore, Notes: "pet"}},
+		},
+		{
+			name:        "Comments and blank lines",
+			input:       "# my list\n\n  \ncat;40\n",
+			maxWords:    10,
+			wantEntries: []Entry{{Word: "CAT", Score: 40}},
+		},
+		{
+			name:        "Repeated word keeps the last entry",
+			input:       "cat;40\ndog;30\nCAT;70\n",
+			maxWords:    2,
+			wantEntries: []Entry{{Word: "CAT", Score: 70}, {Word: "DOG", Score: 30}},
+		},
+		{
+			name:        "Bad lines",
+			input:       "123\ncat;abc\ndog;101\n" + strings.Repeat("a", MaxWordLen+1) + "\nemu;-1\nowl;" + "\nyak;5;" + strings.Repeat("x", MaxNotesLen+1) + "\n",
+			maxWords:    10,
+			wantEntries: []Entry{{Word: "OWL", Score: DefaultScore}},
+			wantErrors: []LineError{
+				{1, "word must contain between 1 and 25 letters"},
+				{2, "score must be an integer between 0 and 100"},
+				{3, "score must be an integer between 0 and 100"},
+				{4, "word must contain between 1 and 25 letters"},
+				{5, "score must be an integer between 0 and 100"},
+				{7, "notes must not be more than 500 characters long"},
+			},
+		},
+		{
+			name:     "Too many words",
+			input:    "cat\ndog\nemu\n",
+			maxWords: 2,
+			wantErr:  true,
+		},
+		{
+			name:     "Empty",
+			input:    "",
+			maxWords: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, errs, err := Parse(strings.NewReader(tt.input), tt.maxWords)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error; want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, tt.wantEntries) {
+				t.Errorf("got entries %+v; want %+v", entries, tt.wantEntries)
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrors) {
+				t.Errorf("got line errors %+v; want %+v", errs, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS puzzles_created_at_idx;
+DROP INDEX IF EXISTS puzzles_author_id_idx;
+DROP INDEX IF EXISTS puzzles_search_idx;
+DROP TRIGGER IF EXISTS puzzles_search_trigger ON puzzles;
+DROP FUNCTION IF EXISTS puzzles_search_update();
+ALTER TABLE puzzles DROP COLUMN IF EXISTS search;
@@ -0,0 +1,21 @@
+ALTER TABLE puzzles ADD COLUMN search TSVECTOR NOT NULL DEFAULT ''::tsvector;
+
+CREATE FUNCTION puzzles_search_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search :=
+        setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.description, '')), 'B') ||
+        setweight(jsonb_to_tsvector('english', coalesce(NEW.content::jsonb, '{}'::jsonb), '["string"]'), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER puzzles_search_trigger
+    BEFORE INSERT OR UPDATE OF title, description, content ON puzzles
+    FOR EACH ROW EXECUTE FUNCTION puzzles_search_update();
+
+UPDATE puzzles SET title = title;
+
+CREATE INDEX IF NOT EXISTS puzzles_search_idx ON puzzles USING GIN (search);
+CREATE INDEX IF NOT EXISTS puzzles_author_id_idx ON puzzles (author_id);
+CREATE INDEX IF NOT EXISTS puzzles_created_at_idx ON puzzles (created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS puzzles_title_id_idx;
+DROP INDEX IF EXISTS puzzles_published_at_id_idx;
+DROP INDEX IF EXISTS puzzles_updated_at_id_idx;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS published_at;
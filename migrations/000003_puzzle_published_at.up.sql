@@ -0,0 +1,7 @@
+ALTER TABLE puzzles ADD COLUMN published_at TIMESTAMPTZ;
+
+UPDATE puzzles SET published_at = updated_at WHERE published;
+
+CREATE INDEX IF NOT EXISTS puzzles_updated_at_id_idx ON puzzles (updated_at, id);
+CREATE INDEX IF NOT EXISTS puzzles_published_at_id_idx ON puzzles (COALESCE(published_at, created_at), id);
+CREATE INDEX IF NOT EXISTS puzzles_title_id_idx ON puzzles (title, id);
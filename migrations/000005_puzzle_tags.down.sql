@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS difficulty_votes;
+DROP TABLE IF EXISTS puzzle_tags;
+DROP TABLE IF EXISTS tags;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS difficulty;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS notes;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS theme;
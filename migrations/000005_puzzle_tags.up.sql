@@ -0,0 +1,24 @@
+ALTER TABLE puzzles ADD COLUMN theme TEXT NOT NULL DEFAULT '';
+ALTER TABLE puzzles ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+ALTER TABLE puzzles ADD COLUMN difficulty SMALLINT CHECK (difficulty BETWEEN 1 AND 5);
+
+CREATE TABLE tags (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE puzzle_tags (
+    puzzle_id INT NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (puzzle_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS puzzle_tags_tag_id_idx ON puzzle_tags (tag_id);
+
+CREATE TABLE difficulty_votes (
+    puzzle_id INT NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (puzzle_id, user_id)
+);
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code LIKE 'collections:%';
+DROP TABLE IF EXISTS collection_puzzles;
+DROP TABLE IF EXISTS collections;
@@ -0,0 +1,35 @@
+CREATE TABLE collections (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    cover_url TEXT NOT NULL DEFAULT '',
+    published BOOLEAN NOT NULL DEFAULT FALSE,
+    owner_id INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE collection_puzzles (
+    collection_id INT NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    puzzle_id INT NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    PRIMARY KEY (collection_id, puzzle_id)
+);
+
+CREATE INDEX IF NOT EXISTS collection_puzzles_puzzle_id_idx ON collection_puzzles (puzzle_id);
+
+INSERT INTO permissions (code) VALUES
+('collections:create'),
+('collections:update'),
+('collections:delete')
+ON CONFLICT (code) DO NOTHING;
+
+-- everyone who can create puzzles can also put them together into collections
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT up.user_id, c.id
+FROM users_permissions up
+INNER JOIN permissions p ON up.permission_id = p.id
+CROSS JOIN permissions c
+WHERE p.code = 'puzzles:create' AND c.code LIKE 'collections:%'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code = 'wordlists:house';
+DROP TABLE IF EXISTS words;
+DROP TABLE IF EXISTS word_lists;
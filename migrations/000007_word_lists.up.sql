@@ -0,0 +1,24 @@
+CREATE TABLE word_lists (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL,
+    owner_id INT REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS word_lists_owner_id_idx ON word_lists (owner_id);
+
+CREATE TABLE words (
+    list_id INT NOT NULL REFERENCES word_lists(id) ON DELETE CASCADE,
+    word TEXT NOT NULL,
+    score SMALLINT NOT NULL DEFAULT 50 CHECK (score BETWEEN 0 AND 100),
+    notes TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (list_id, word)
+);
+
+CREATE INDEX IF NOT EXISTS words_length_idx ON words (length(word), word text_pattern_ops);
+
+-- the shared house list is the one without an owner
+INSERT INTO word_lists (name) VALUES ('House');
+
+INSERT INTO permissions (code) VALUES ('wordlists:house') ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,22 @@
+CREATE TABLE clues (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    puzzle_id INT NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    direction TEXT NOT NULL CHECK (direction IN ('across', 'down')),
+    number INT NOT NULL,
+    clue TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    UNIQUE (puzzle_id, direction, number)
+);
+
+CREATE INDEX IF NOT EXISTS clues_answer_idx ON clues (answer);
+
+INSERT INTO clues (puzzle_id, direction, number, clue, answer)
+SELECT p.id, d.direction, e.key::int, COALESCE(e.value->>'clue', ''),
+    upper(regexp_replace(COALESCE(e.value->>'answer', ''), '[^[:alpha:]]', '', 'g'))
+FROM puzzles p
+CROSS JOIN (VALUES ('across'), ('down')) AS d(direction)
+CROSS JOIN LATERAL json_each(
+    CASE json_typeof(p.content->d.direction) WHEN 'object' THEN p.content->d.direction ELSE '{}'::json END
+) e
+WHERE e.key ~ '^[0-9]+$'
+ON CONFLICT DO NOTHING;
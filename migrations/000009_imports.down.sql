@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS puzzles_author_source_hash_idx;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS source_hash;
+DROP TABLE IF EXISTS imports;
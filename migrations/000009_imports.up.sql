@@ -0,0 +1,21 @@
+CREATE TABLE imports (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    checksum TEXT NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    file_count INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    results JSONB NOT NULL DEFAULT '[]',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS imports_owner_checksum_idx ON imports (owner_id, checksum);
+
+-- the hash of the file a puzzle was imported from, so importing the same file
+-- again doesn't create a copy
+ALTER TABLE puzzles ADD COLUMN source_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS puzzles_author_source_hash_idx ON puzzles (author_id, source_hash) WHERE source_hash IS NOT NULL;
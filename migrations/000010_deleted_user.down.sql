@@ -0,0 +1,5 @@
+UPDATE puzzles
+SET author_id = NULL
+WHERE author_id = (SELECT id FROM users WHERE email = 'deleted-user');
+
+DELETE FROM users WHERE email = 'deleted-user';
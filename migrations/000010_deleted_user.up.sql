@@ -0,0 +1,10 @@
+-- puzzles outlive the accounts of the people who wrote them; when an account
+-- is deleted its published puzzles are handed to this placeholder author,
+-- which can't log in because its email isn't a valid address
+INSERT INTO users (full_name, display_name, email, password_hash, activated)
+VALUES ('', 'Former contributor', 'deleted-user', '', FALSE)
+ON CONFLICT (email) DO NOTHING;
+
+UPDATE puzzles
+SET author_id = (SELECT id FROM users WHERE email = 'deleted-user')
+WHERE author_id IS NULL;
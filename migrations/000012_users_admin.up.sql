@@ -0,0 +1 @@
+INSERT INTO permissions (code) VALUES ('users:admin') ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,8 @@
+// Package migrations embeds the database migrations so the binaries can
+// apply them without the migration files being deployed alongside.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS